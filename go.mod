@@ -6,4 +6,5 @@ require (
 	github.com/andlabs/ui v0.0.0-20200610043537-70a69d6ae31e
 	github.com/getlantern/systray v1.2.2
 	github.com/shirou/gopsutil/v3 v3.23.10
+	golang.org/x/sys v0.13.0
 )
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"fmt"
 	"log"
 	"sync"
 	"time"
@@ -12,11 +13,21 @@ import (
 
 // Application represents the main application
 type Application struct {
-	settings    *settings.Config
-	tray        ui.TrayInterface
-	monitoring  bool
-	wg          sync.WaitGroup
-	refreshChan chan struct{} // Channel to signal settings updates
+	settings       *settings.Config
+	tray           ui.TrayInterface
+	monitoring     bool
+	wg             sync.WaitGroup
+	refreshChan    chan struct{} // Channel to signal settings updates
+	watches        []*metrics.ProcessWatch
+	watchErrors    map[int]error // Why watches were skipped, by position in the config
+	commands       []*metrics.CommandMetric
+	commandErrors  map[int]error // Why command metrics were skipped, by position in the config
+	endpoints      []*metrics.HTTPMetric
+	endpointErrors map[int]error // Why HTTP metrics were skipped, by position in the config
+	plugins        []*metrics.Plugin
+	pluginErrors   map[int]error // Why plugins were skipped, by position in the config
+	dirScanner     *metrics.DirectoryScanner
+	mounts         *metrics.MountWatcher
 }
 
 // NewApplication creates a new application instance
@@ -37,6 +48,106 @@ func (a *Application) Run() error {
 	}
 	a.settings = s
 
+	// Point the cgroup based collectors at the configured hierarchy
+	metrics.SetCgroupRoot(a.settings.CgroupRoot)
+
+	// Inside a container, host-wide totals are misleading, so use the cgroup limits
+	if a.settings.ContainerAware && metrics.DetectContainer() {
+		log.Println("Running in a container, reporting usage against cgroup limits")
+		metrics.SetContainerMode(true)
+	}
+
+	// Set up the process watches defined in the config file. A watch that can't be
+	// set up keeps its menu item, which shows why.
+	a.watchErrors = make(map[int]error)
+	watchLabels := make(labelSet)
+	for n, w := range a.settings.ProcessWatches {
+		watch, err := metrics.NewProcessWatch(w.DisplayLabel(), w.Name, w.Cmdline, w.Pidfile)
+		if err == nil {
+			err = watchLabels.add("process watch", w.DisplayLabel())
+		}
+		if err != nil {
+			log.Printf("Skipping process watch: %v", err)
+			a.watchErrors[n] = err
+			continue
+		}
+		a.watches = append(a.watches, watch)
+	}
+
+	// Set up the command metrics defined in the config file. Command and HTTP
+	// metrics share the title, so their labels must differ from each other's.
+	a.commandErrors = make(map[int]error)
+	customLabels := make(labelSet)
+	for n, c := range a.settings.CommandMetrics {
+		command, err := metrics.NewCommandMetric(c.Label, c.Command,
+			time.Duration(c.Interval)*time.Second, time.Duration(c.Timeout)*time.Second,
+			c.MaxOutput, c.Pattern, c.JSONPath, c.Unit)
+		if err == nil {
+			err = customLabels.add("command metric", c.Label)
+		}
+		if err != nil {
+			log.Printf("Skipping command metric: %v", err)
+			a.commandErrors[n] = err
+			continue
+		}
+		a.commands = append(a.commands, command)
+	}
+
+	// Set up the HTTP endpoint metrics defined in the config file
+	a.endpointErrors = make(map[int]error)
+	for n, h := range a.settings.HTTPMetrics {
+		endpoint, err := metrics.NewHTTPMetric(h.Label, metrics.HTTPMetricOptions{
+			URL:       h.URL,
+			Headers:   h.Headers,
+			Token:     h.Token,
+			TokenFile: h.TokenFile,
+			Interval:  time.Duration(h.Interval) * time.Second,
+			Timeout:   time.Duration(h.Timeout) * time.Second,
+			JSONPath:  h.JSONPath,
+			Unit:      h.Unit,
+			Thresholds: metrics.Thresholds{
+				WarnAbove:     h.WarnAbove,
+				CriticalAbove: h.CriticalAbove,
+				WarnBelow:     h.WarnBelow,
+				CriticalBelow: h.CriticalBelow,
+			},
+		})
+		if err == nil {
+			err = customLabels.add("HTTP metric", h.Label)
+		}
+		if err != nil {
+			log.Printf("Skipping HTTP metric: %v", err)
+			a.endpointErrors[n] = err
+			continue
+		}
+		a.endpoints = append(a.endpoints, endpoint)
+	}
+
+	// Set up the plugins defined in the config file
+	a.pluginErrors = make(map[int]error)
+	pluginLabels := make(labelSet)
+	for n, p := range a.settings.Plugins {
+		plugin, err := metrics.NewPlugin(p.Label, p.Command, p.Args)
+		if err == nil {
+			err = pluginLabels.add("plugin", p.Label)
+		}
+		if err != nil {
+			log.Printf("Skipping plugin: %v", err)
+			a.pluginErrors[n] = err
+			continue
+		}
+		a.plugins = append(a.plugins, plugin)
+	}
+
+	// Directory sizes are computed in the background, as a scan can take minutes
+	if len(a.settings.WatchedDirectories) > 0 {
+		interval := time.Duration(a.settings.DirectoryScanInterval) * time.Minute
+		a.dirScanner = metrics.NewDirectoryScanner(a.settings.WatchedDirectories, interval)
+	}
+
+	// Removable drives are picked up as they are mounted, so the watcher always runs
+	a.mounts = metrics.NewMountWatcher()
+
 	// Initialize the UI with SysTray implementation
 	a.tray = ui.NewTrayWithCallback(a.settings, a.onSettingsChanged)
 
@@ -67,6 +178,22 @@ func (a *Application) Start() {
 	a.monitoring = true
 	a.wg.Add(1)
 	go a.monitor()
+
+	if a.dirScanner != nil {
+		a.dirScanner.Start()
+	}
+	if a.mounts != nil {
+		a.mounts.Start()
+	}
+	for _, c := range a.commands {
+		c.Start()
+	}
+	for _, e := range a.endpoints {
+		e.Start()
+	}
+	for _, p := range a.plugins {
+		p.Start()
+	}
 }
 
 // Stop stops monitoring system metrics
@@ -74,6 +201,31 @@ func (a *Application) Stop() {
 	a.monitoring = false
 	a.wg.Wait()
 
+	// Cancel any directory scan in progress
+	if a.dirScanner != nil {
+		a.dirScanner.Stop()
+	}
+	if a.mounts != nil {
+		a.mounts.Stop()
+	}
+	for _, c := range a.commands {
+		c.Stop()
+	}
+	for _, e := range a.endpoints {
+		e.Stop()
+	}
+
+	// Plugins get a few seconds each to exit, so stop them together
+	var plugins sync.WaitGroup
+	for _, p := range a.plugins {
+		plugins.Add(1)
+		go func(p *metrics.Plugin) {
+			defer plugins.Done()
+			p.Stop()
+		}(p)
+	}
+	plugins.Wait()
+
 	// Stop the tray
 	if a.tray != nil {
 		a.tray.Stop()
@@ -151,6 +303,253 @@ func updateMetrics(a *Application) {
 		log.Printf("Failed to get network usage: %v", err)
 	}
 
+	// Get systemd service usage for the services submenu and pinned services
+	if a.settings.ShowServices || len(a.settings.PinnedServices) > 0 {
+		services, err := metrics.GetServiceUsage()
+		if err != nil {
+			log.Printf("Failed to get service usage: %v", err)
+		} else {
+			a.tray.UpdateServices(services)
+		}
+	}
+
+	// Get container usage from the Docker-compatible engine
+	if a.settings.ShowContainers {
+		containers, err := metrics.GetContainerStats(a.settings.ContainerSocket)
+		if err != nil {
+			log.Printf("Failed to get container stats: %v", err)
+		} else {
+			a.tray.UpdateContainers(containers)
+		}
+	}
+
+	// Get kernel scheduler activity and process counts
+	if a.settings.ShowKernel {
+		activity, err := metrics.GetKernelActivity()
+		if err != nil {
+			log.Printf("Failed to get kernel activity: %v", err)
+		} else {
+			a.tray.UpdateKernelActivity(activity)
+		}
+	}
+
+	// Get system identity, uptime and login sessions
+	if a.settings.ShowSystemInfo {
+		info, err := metrics.GetSystemInfo()
+		if err != nil {
+			log.Printf("Failed to get system info: %v", err)
+		} else {
+			a.tray.UpdateSystemInfo(info)
+		}
+	}
+
+	// Get pending reboots, failed units and updates; systemctl runs in the background every minute
+	if a.settings.ShowMaintenance {
+		if status, ok := metrics.GetMaintenanceStatus(a.settings.SystemctlPath); ok {
+			a.tray.UpdateMaintenance(status)
+		}
+	}
+
+	// Get file handle and inotify usage before the title is rebuilt
+	if a.settings.ShowFileHandles {
+		usage, err := metrics.GetFileHandleUsage(a.settings.TopProcessCount)
+		if err != nil {
+			log.Printf("Failed to get file handle usage: %v", err)
+		} else {
+			a.tray.UpdateFileHandles(usage)
+		}
+	}
+
+	// Check for thermal throttling before the CPU item and title are rebuilt
+	if a.settings.DetectThrottling && a.settings.ShowCPU {
+		status, err := metrics.GetThrottleStatus()
+		if err != nil {
+			log.Printf("Failed to get throttle status: %v", err)
+		} else {
+			a.tray.UpdateThrottleStatus(status)
+		}
+	}
+
+	// Get CPU power draw before the title is rebuilt
+	if a.settings.ShowPower {
+		usage, err := metrics.GetPowerUsage()
+		if err != nil {
+			log.Printf("Failed to get CPU power: %v", err)
+		}
+		a.tray.UpdatePower(usage, err)
+	}
+
+	// Get RAID and read-only filesystem state before the title is rebuilt
+	if a.settings.ShowStorageHealth {
+		health, err := metrics.GetStorageHealth()
+		if err != nil {
+			log.Printf("Failed to get storage health: %v", err)
+		} else {
+			a.tray.UpdateStorageHealth(health)
+		}
+	}
+
+	// Get SMART health; smartctl runs in the background every SmartInterval minutes
+	if a.settings.ShowDriveHealth && len(a.settings.SmartDevices) > 0 {
+		interval := time.Duration(a.settings.SmartInterval) * time.Minute
+		a.tray.UpdateDriveHealth(metrics.GetDriveHealth(a.settings.SmartctlPath, a.settings.SmartDevices, interval))
+	}
+
+	// Get swap and page fault activity before the title is rebuilt
+	if a.settings.ShowSwapActivity {
+		activity, err := metrics.GetVMActivity()
+		if err != nil {
+			log.Printf("Failed to get virtual memory activity: %v", err)
+		} else {
+			a.tray.UpdateVMActivity(activity)
+		}
+	}
+
+	// Get watched process usage before the title is rebuilt
+	if len(a.settings.ProcessWatches) > 0 {
+		stats, err := metrics.GetWatchedProcesses(a.watches)
+		if err != nil {
+			log.Printf("Failed to get watched processes: %v", err)
+		} else {
+			a.tray.UpdateWatchedProcesses(a.configuredWatches(stats))
+		}
+	}
+
+	// Command and HTTP metrics run on their own intervals, so this only picks up their latest values
+	if len(a.settings.CommandMetrics) > 0 || len(a.settings.HTTPMetrics) > 0 {
+		commands := make([]string, len(a.settings.CommandMetrics))
+		for n, c := range a.settings.CommandMetrics {
+			commands[n] = c.Label
+		}
+		endpoints := make([]string, len(a.settings.HTTPMetrics))
+		for n, h := range a.settings.HTTPMetrics {
+			endpoints[n] = h.Label
+		}
+		a.tray.UpdateCustomMetrics(
+			configuredValues(commands, a.commandErrors, metrics.GetCommandMetrics(a.commands)),
+			configuredValues(endpoints, a.endpointErrors, metrics.GetHTTPMetrics(a.endpoints)))
+	}
+
+	// Plugins send updates as they have them, so this only picks up their latest values
+	if len(a.settings.Plugins) > 0 {
+		a.tray.UpdatePlugins(a.configuredPlugins(metrics.GetPluginStatuses(a.plugins)))
+	}
+
+	// Get the latest sizes of the watched directories
+	if a.dirScanner != nil {
+		a.tray.UpdateDirectories(a.dirScanner.Results())
+	}
+
+	// List the removable drives mounted since the last update
+	if a.settings.ShowRemovableDrives && a.mounts != nil {
+		a.tray.UpdateRemovableDrives(a.mounts.Drives())
+	}
+
+	// Offer the mount points for disk usage analysis, as of the last change to the mount table
+	if a.mounts != nil {
+		a.tray.UpdateMountPoints(a.mounts.MountPoints())
+	}
+
 	// Update the tray with the latest metrics
 	a.tray.UpdateMetrics(cpuUsage, memUsage, diskUsage, netUsage)
+
+	// Get the top processes, or applications if grouping is enabled
+	if a.settings.ShowProcesses && a.settings.GroupProcessesByApp {
+		groups, err := metrics.GetAppGroups(a.settings.TopProcessCount)
+		if err != nil {
+			log.Printf("Failed to get application groups: %v", err)
+		} else {
+			a.tray.UpdateAppGroups(groups)
+		}
+	} else if a.settings.ShowProcesses {
+		procs, err := metrics.GetTopProcesses(a.settings.TopProcessCount)
+		if err != nil {
+			log.Printf("Failed to get top processes: %v", err)
+		} else {
+			a.tray.UpdateProcesses(procs)
+		}
+	}
+
+	// Get the processes doing the most disk I/O
+	if a.settings.ShowDiskIO {
+		procs, err := metrics.GetTopDiskIO(a.settings.TopProcessCount)
+		if err != nil {
+			log.Printf("Failed to get disk I/O: %v", err)
+		} else {
+			a.tray.UpdateDiskIO(procs)
+		}
+	}
+
+	// Get usage aggregated by user
+	if a.settings.ShowUsers {
+		users, err := metrics.GetUserUsage()
+		if err != nil {
+			log.Printf("Failed to get per-user usage: %v", err)
+		} else {
+			a.tray.UpdateUsers(users)
+		}
+	}
+}
+
+// configuredWatches returns the stats of every watch in the config file, in order,
+// given those of the watches that were set up. The others report why they were skipped.
+func (a *Application) configuredWatches(stats []metrics.WatchedProcessStats) []metrics.WatchedProcessStats {
+	all := make([]metrics.WatchedProcessStats, 0, len(a.settings.ProcessWatches))
+	for n, w := range a.settings.ProcessWatches {
+		if err, ok := a.watchErrors[n]; ok {
+			all = append(all, metrics.WatchedProcessStats{Label: w.DisplayLabel(), Error: err.Error()})
+			continue
+		}
+		all = append(all, stats[0])
+		stats = stats[1:]
+	}
+	return all
+}
+
+// configuredValues returns the values of every metric in the config file, in
+// order, given the labels from the config and the values of the metrics that
+// were set up. The others report why they were skipped.
+func configuredValues(labels []string, errs map[int]error, values []metrics.CustomMetricValue) []metrics.CustomMetricValue {
+	all := make([]metrics.CustomMetricValue, 0, len(labels))
+	for n, label := range labels {
+		if err, ok := errs[n]; ok {
+			all = append(all, metrics.CustomMetricValue{Label: label, Error: err.Error()})
+			continue
+		}
+		all = append(all, values[0])
+		values = values[1:]
+	}
+	return all
+}
+
+// configuredPlugins returns the status of every plugin in the config file, in
+// order, given those of the plugins that were set up. The others report why
+// they were skipped.
+func (a *Application) configuredPlugins(statuses []metrics.PluginStatus) []metrics.PluginStatus {
+	all := make([]metrics.PluginStatus, 0, len(a.settings.Plugins))
+	for n, p := range a.settings.Plugins {
+		if err, ok := a.pluginErrors[n]; ok {
+			all = append(all, metrics.PluginStatus{Label: p.Label, Error: err.Error()})
+			continue
+		}
+		all = append(all, statuses[0])
+		statuses = statuses[1:]
+	}
+	return all
+}
+
+// labelSet holds the labels already in use by one kind of metric. Metrics with
+// the same label, or none, couldn't be told apart in the menu and title.
+type labelSet map[string]bool
+
+// add claims label, or returns why it can't be used
+func (s labelSet) add(kind, label string) error {
+	if label == "" {
+		return fmt.Errorf("%s needs a label", kind)
+	}
+	if s[label] {
+		return fmt.Errorf("%s %q has the same label as another", kind, label)
+	}
+	s[label] = true
+	return nil
 }
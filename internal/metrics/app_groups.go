@@ -0,0 +1,192 @@
+package metrics
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+var (
+	lastGroupCPUTimes   = make(map[int32]float64)
+	lastGroupSampleTime time.Time
+	appGroupMutex       sync.Mutex
+)
+
+// AppGroup contains the combined usage of the processes belonging to one application
+type AppGroup struct {
+	Name         string
+	Scope        string  // cgroup path of the application scope, empty for process trees
+	CPUPercent   float64 // Combined CPU usage, 100% = one core
+	Memory       uint64  // Bytes, from memory.current for scopes or summed RSS for process trees
+	ProcessCount int
+}
+
+// groupProc holds what is needed to place a process in a group
+type groupProc struct {
+	proc *process.Process
+	ppid int32
+	name string
+}
+
+// GetAppGroups returns up to limit applications ordered by CPU usage.
+// Processes are grouped by the app-*.scope cgroup desktops launch applications in,
+// and by the tree below their session's top-level process otherwise.
+func GetAppGroups(limit int) ([]AppGroup, error) {
+	appGroupMutex.Lock()
+	defer appGroupMutex.Unlock()
+
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	timeDiff := now.Sub(lastGroupSampleTime).Seconds()
+	cpuTimes := make(map[int32]float64, len(procs))
+
+	table := make(map[int32]groupProc, len(procs))
+	for _, p := range procs {
+		ppid, err := p.Ppid()
+		if err != nil {
+			continue
+		}
+		name, err := p.Name()
+		if err != nil {
+			continue
+		}
+		table[p.Pid] = groupProc{proc: p, ppid: ppid, name: name}
+	}
+
+	groups := make(map[string]*AppGroup)
+	for pid, gp := range table {
+		// Kernel threads use no user memory and are not applications
+		if pid == 2 || gp.ppid == 2 {
+			continue
+		}
+
+		// Groups are keyed by scope path, or by the PID of the tree's root process
+		template := AppGroup{Scope: appScope(pid)}
+		key := template.Scope
+		if key != "" {
+			template.Name = scopeAppName(path.Base(key))
+		} else {
+			root := treeRoot(pid, table)
+			key = "pid:" + strconv.Itoa(int(root))
+			template.Name = table[root].name
+		}
+
+		group, ok := groups[key]
+		if !ok {
+			group = &template
+			groups[key] = group
+		}
+
+		group.ProcessCount++
+
+		if times, err := gp.proc.Times(); err == nil {
+			total := times.User + times.System
+			cpuTimes[pid] = total
+			if last, ok := lastGroupCPUTimes[pid]; ok && timeDiff > 0 && total >= last {
+				group.CPUPercent += (total - last) / timeDiff * 100
+			}
+		}
+
+		if group.Scope == "" {
+			if memInfo, err := gp.proc.MemoryInfo(); err == nil {
+				group.Memory += memInfo.RSS
+			}
+		}
+	}
+
+	// Update last values
+	lastGroupCPUTimes = cpuTimes
+	lastGroupSampleTime = now
+
+	result := make([]AppGroup, 0, len(groups))
+	for _, group := range groups {
+		if group.Scope != "" {
+			if memory, err := readCgroupUint(group.Scope, "memory.current"); err == nil {
+				group.Memory = memory
+			}
+		}
+		result = append(result, *group)
+	}
+
+	sort.Slice(result, func(a, b int) bool {
+		if result[a].CPUPercent == result[b].CPUPercent {
+			return result[a].Memory > result[b].Memory
+		}
+		return result[a].CPUPercent > result[b].CPUPercent
+	})
+
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+// appScope returns the cgroup path of the application scope a process runs in, if any
+func appScope(pid int32) string {
+	cgroup := processCgroup(pid)
+	for dir := cgroup; dir != "/" && dir != "." && dir != ""; dir = path.Dir(dir) {
+		if scopeAppName(path.Base(dir)) != "" {
+			return dir
+		}
+	}
+	return ""
+}
+
+// scopeAppName extracts the application ID from a scope unit name such as
+// "app-gnome-google\x2dchrome-12345.scope" or "snap.firefox.firefox-<uuid>.scope".
+// It returns "" for units that are not application scopes.
+func scopeAppName(unit string) string {
+	if !strings.HasSuffix(unit, ".scope") {
+		return ""
+	}
+	name := strings.TrimSuffix(unit, ".scope")
+
+	switch {
+	case strings.HasPrefix(name, "snap."):
+		parts := strings.Split(name, ".")
+		if len(parts) < 2 {
+			return ""
+		}
+		return parts[1]
+	case strings.HasPrefix(name, "app-"):
+		name = strings.TrimPrefix(name, "app-")
+	default:
+		return ""
+	}
+
+	// Drop the random suffix that makes each scope unique
+	if idx := strings.LastIndex(name, "-"); idx > 0 {
+		name = name[:idx]
+	}
+
+	// Drop the launcher, e.g. "gnome-"; dashes within the ID are escaped as \x2d
+	if idx := strings.Index(name, "-"); idx > 0 {
+		name = name[idx+1:]
+	}
+
+	return strings.ReplaceAll(name, `\x2d`, "-")
+}
+
+// treeRoot walks up the parent chain to the process just below init or a systemd
+// user manager, which is the closest thing to an application without scopes
+func treeRoot(pid int32, table map[int32]groupProc) int32 {
+	for depth := 0; depth < 64; depth++ {
+		ppid := table[pid].ppid
+		parent, ok := table[ppid]
+		if !ok || ppid <= 1 || parent.name == "systemd" {
+			return pid
+		}
+		pid = ppid
+	}
+	return pid
+}
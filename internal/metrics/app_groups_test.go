@@ -0,0 +1,33 @@
+package metrics
+
+import "testing"
+
+func TestScopeAppName(t *testing.T) {
+	tests := []struct {
+		unit string
+		want string
+	}{
+		{"app-gnome-firefox-1234.scope", "firefox"},
+		// Dashes within the ID are escaped
+		{`app-gnome-google\x2dchrome-12345.scope`, "google-chrome"},
+		{"app-flatpak-org.mozilla.firefox-2851.scope", "org.mozilla.firefox"},
+		{"app-kde-org.kde.konsole-98765.scope", "org.kde.konsole"},
+		// Without a launcher, only the unique suffix is dropped
+		{"app-org.gnome.Terminal-4821.scope", "org.gnome.Terminal"},
+		{"snap.firefox.firefox-1d3e7c5a-6f3b-4b2e-9a51-0c2f1e7d8b90.scope", "firefox"},
+		{"snap.code.code-3a5b.scope", "code"},
+
+		// Not application scopes
+		{"app-gnome-firefox-1234.service", ""},
+		{"session-2.scope", ""},
+		{"docker-4f2a9c.scope", ""},
+		{"init.scope", ""},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if got := scopeAppName(test.unit); got != test.want {
+			t.Errorf("scopeAppName(%q) = %q, want %q", test.unit, got, test.want)
+		}
+	}
+}
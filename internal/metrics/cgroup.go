@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	cgroupRoot  = "/sys/fs/cgroup"
+	cgroupMutex sync.Mutex
+)
+
+// SetCgroupRoot changes where the unified cgroup v2 hierarchy is read from
+func SetCgroupRoot(root string) {
+	cgroupMutex.Lock()
+	defer cgroupMutex.Unlock()
+
+	if root != "" {
+		cgroupRoot = root
+	}
+}
+
+// cgroupPath returns the filesystem path of a file in a cgroup directory
+func cgroupPath(cgroup, file string) string {
+	cgroupMutex.Lock()
+	defer cgroupMutex.Unlock()
+
+	return filepath.Join(cgroupRoot, cgroup, file)
+}
+
+// processCgroup returns the cgroup v2 path of a process, or "" on cgroup v1 systems
+func processCgroup(pid int32) string {
+	file, err := os.Open(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// The unified hierarchy is the entry with ID 0 and no controllers
+		if line := scanner.Text(); strings.HasPrefix(line, "0::") {
+			return strings.TrimPrefix(line, "0::")
+		}
+	}
+
+	return ""
+}
+
+// readCgroupUint reads a single number from a file in a cgroup directory
+func readCgroupUint(cgroup, file string) (uint64, error) {
+	return readUintFile(cgroupPath(cgroup, file))
+}
+
+// readUintFile reads a file containing a single number
+func readUintFile(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// readCgroupKeyed reads a flat keyed file such as cpu.stat ("key value" per line)
+func readCgroupKeyed(cgroup, file string) (map[string]uint64, error) {
+	return readKeyedFile(cgroupPath(cgroup, file))
+}
+
+// readKeyedFile reads a file with one "key value" pair per line
+func readKeyedFile(path string) (map[string]uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]uint64)
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if value, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[fields[0]] = value
+		}
+	}
+
+	return values, nil
+}
+
+// readCgroupIOBytes returns the bytes read and written by a cgroup across all devices, from io.stat
+func readCgroupIOBytes(cgroup string) (uint64, uint64, error) {
+	data, err := os.ReadFile(cgroupPath(cgroup, "io.stat"))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Each line looks like "8:0 rbytes=1024 wbytes=2048 rios=1 wios=2 dbytes=0 dios=0"
+	var read, written uint64
+	for _, line := range strings.Split(string(data), "\n") {
+		for _, field := range strings.Fields(line) {
+			eq := strings.Index(field, "=")
+			if eq < 0 {
+				continue
+			}
+			value, err := strconv.ParseUint(field[eq+1:], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch field[:eq] {
+			case "rbytes":
+				read += value
+			case "wbytes":
+				written += value
+			}
+		}
+	}
+
+	return read, written, nil
+}
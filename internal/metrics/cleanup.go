@@ -0,0 +1,256 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// cleanupTimeout bounds a cleaning command; removing a large module cache takes a while
+	cleanupTimeout = 10 * time.Minute
+
+	// toolTimeout bounds commands used to locate caches, such as `go env`
+	toolTimeout = 10 * time.Second
+)
+
+// CleanupOptions configures the locations checked by FindReclaimableSpace
+type CleanupOptions struct {
+	ContainerSocket   string   // Docker-compatible API socket used to size dangling images
+	ImagePruneCommand []string // Command that removes dangling images
+}
+
+// ReclaimableSpace is a well-known location whose contents can usually be deleted
+type ReclaimableSpace struct {
+	Name  string
+	Path  string // Directory that was measured, if the space is in one place
+	Size  uint64
+	Hint  string // How to reclaim the space when it cannot be cleaned from here
+	Error string // Why the location could not be measured, if it couldn't
+	clean func() error
+}
+
+// Cleanable reports whether Clean can reclaim the space without root
+func (r ReclaimableSpace) Cleanable() bool {
+	return r.clean != nil
+}
+
+// Clean deletes the location's contents
+func (r ReclaimableSpace) Clean() error {
+	if r.clean == nil {
+		return os.ErrPermission
+	}
+	return r.clean()
+}
+
+// FindReclaimableSpace measures the user's Trash and caches, the systemd journal,
+// disabled snap revisions and dangling container images. Locations that don't
+// exist on this machine are left out. Measuring can take a while for large caches
+// and stops early if ctx is cancelled.
+func FindReclaimableSpace(ctx context.Context, opts CleanupOptions) []ReclaimableSpace {
+	// Without a home directory the home-based paths would be relative to the
+	// working directory, and cleaning them would delete the wrong files
+	home, err := os.UserHomeDir()
+	if err != nil || !filepath.IsAbs(home) {
+		home = ""
+	}
+	goCache, goModCache := goCacheDirs(home)
+
+	trash := filepath.Join(home, ".local", "share", "Trash")
+
+	// The Go module cache is read-only on disk, so the go command has to remove it
+	var cleanGoCache, cleanGoModCache, cleanNpmCache func() error
+	if _, err := exec.LookPath("go"); err == nil {
+		cleanGoCache = func() error { return runCleanup("go", "clean", "-cache") }
+		cleanGoModCache = func() error { return runCleanup("go", "clean", "-modcache") }
+	}
+	if _, err := exec.LookPath("npm"); err == nil {
+		cleanNpmCache = func() error { return runCleanup("npm", "cache", "clean", "--force") }
+	}
+
+	candidates := []ReclaimableSpace{
+		{Name: "Trash", Path: trash, clean: func() error {
+			return removeContents(filepath.Join(trash, "files"), filepath.Join(trash, "info"), filepath.Join(trash, "expunged"))
+		}},
+		{Name: "User cache", Path: filepath.Join(home, ".cache"), clean: func() error {
+			return removeContents(filepath.Join(home, ".cache"))
+		}},
+		{Name: "Go build cache", Path: goCache, clean: cleanGoCache},
+		{Name: "Go module cache", Path: goModCache, clean: cleanGoModCache},
+		{Name: "npm cache", Path: filepath.Join(home, ".npm", "_cacache"), clean: cleanNpmCache},
+		{Name: "pip cache", Path: filepath.Join(home, ".cache", "pip"), clean: func() error {
+			return removeContents(filepath.Join(home, ".cache", "pip"))
+		}},
+		{Name: "systemd journal", Path: "/var/log/journal",
+			Hint: "sudo journalctl --vacuum-time=2weeks"},
+	}
+
+	var found []ReclaimableSpace
+	for _, c := range candidates {
+		if !filepath.IsAbs(c.Path) {
+			continue
+		}
+		if _, err := os.Stat(c.Path); err != nil {
+			continue
+		}
+
+		size, _, err := directorySize(ctx, c.Path)
+		if ctx.Err() != nil {
+			return found
+		}
+		if err != nil {
+			c.Error = err.Error()
+		}
+		c.Size = size
+		found = append(found, c)
+	}
+
+	if snaps, ok := disabledSnapRevisions(); ok {
+		found = append(found, snaps)
+	}
+
+	if images, ok := danglingImages(opts); ok {
+		found = append(found, images)
+	}
+
+	return found
+}
+
+// ReclaimableTotal returns the combined size of locations, counting locations
+// nested in another one, such as the pip cache inside the user cache, only once
+func ReclaimableTotal(locations []ReclaimableSpace) uint64 {
+	var total uint64
+	for _, l := range locations {
+		nested := false
+		for _, other := range locations {
+			if l.Path != "" && other.Path != "" && strings.HasPrefix(l.Path, other.Path+string(filepath.Separator)) {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			total += l.Size
+		}
+	}
+	return total
+}
+
+// goCacheDirs returns the Go build and module cache directories
+func goCacheDirs(home string) (string, string) {
+	goCache := filepath.Join(home, ".cache", "go-build")
+	goModCache := filepath.Join(home, "go", "pkg", "mod")
+
+	if output, err := runCommand(toolTimeout, "go", "env", "GOCACHE", "GOMODCACHE"); err == nil {
+		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+		if len(lines) == 2 {
+			goCache, goModCache = lines[0], lines[1]
+		}
+	}
+
+	return goCache, goModCache
+}
+
+// disabledSnapRevisions sums the packages of snap revisions that are kept for
+// rollback but not in use; snapd keeps up to two of these per snap by default
+func disabledSnapRevisions() (ReclaimableSpace, bool) {
+	files, _ := filepath.Glob("/var/lib/snapd/snaps/*.snap")
+	if len(files) == 0 {
+		return ReclaimableSpace{}, false
+	}
+
+	space := ReclaimableSpace{
+		Name: "Old snap revisions",
+		Hint: "sudo snap set system refresh.retain=2, or snap remove --revision for each disabled revision",
+	}
+
+	// Packages are named <snap>_<revision>.snap
+	for _, file := range files {
+		base := strings.TrimSuffix(filepath.Base(file), ".snap")
+		sep := strings.LastIndex(base, "_")
+		if sep < 0 {
+			continue
+		}
+
+		current, err := os.Readlink(filepath.Join("/snap", base[:sep], "current"))
+		if err != nil || current == base[sep+1:] {
+			continue
+		}
+
+		if info, err := os.Stat(file); err == nil {
+			space.Size += uint64(info.Size())
+		}
+	}
+
+	return space, true
+}
+
+// danglingImages sums the container images that are no longer tagged or used
+func danglingImages(opts CleanupOptions) (ReclaimableSpace, bool) {
+	if opts.ContainerSocket == "" {
+		return ReclaimableSpace{}, false
+	}
+	if _, err := os.Stat(opts.ContainerSocket); err != nil {
+		return ReclaimableSpace{}, false
+	}
+
+	space := ReclaimableSpace{Name: "Dangling container images"}
+
+	client := newSocketClient(opts.ContainerSocket)
+	defer client.CloseIdleConnections()
+
+	var images []struct {
+		Size int64 `json:"Size"`
+	}
+	filters := url.QueryEscape(`{"dangling":["true"]}`)
+	if err := getJSON(client, "/images/json?filters="+filters, &images); err != nil {
+		space.Error = err.Error()
+		return space, true
+	}
+	for _, image := range images {
+		space.Size += uint64(image.Size)
+	}
+
+	if len(opts.ImagePruneCommand) > 0 {
+		space.clean = func() error {
+			return runCleanup(opts.ImagePruneCommand[0], opts.ImagePruneCommand[1:]...)
+		}
+	}
+
+	return space, true
+}
+
+// runCleanup runs a cleaning command, reporting what it wrote to standard error if it fails
+func runCleanup(name string, args ...string) error {
+	if _, err := runCommand(cleanupTimeout, name, args...); err != nil {
+		return fmt.Errorf("%s %s: %s", name, strings.Join(args, " "), commandError(err))
+	}
+	return nil
+}
+
+// removeContents deletes everything inside each directory, keeping the directories themselves
+func removeContents(dirs ...string) error {
+	var firstErr error
+	for _, dir := range dirs {
+		if !filepath.IsAbs(dir) {
+			return fmt.Errorf("refusing to clean relative path %s", dir)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if !os.IsNotExist(err) && firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for _, entry := range entries {
+			if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
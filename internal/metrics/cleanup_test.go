@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindReclaimableSpaceWithoutHome(t *testing.T) {
+	// Started from a directory that looks like a home directory, with HOME unset
+	dir := t.TempDir()
+	writeFixture(t, dir, filepath.Join(".cache", "important"), "keep me")
+	writeFixture(t, dir, filepath.Join(".local", "share", "Trash", "files", "important"), "keep me")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	home, hadHome := os.LookupEnv("HOME")
+	os.Unsetenv("HOME")
+	t.Cleanup(func() {
+		if hadHome {
+			os.Setenv("HOME", home)
+		}
+	})
+
+	for _, space := range FindReclaimableSpace(context.Background(), CleanupOptions{}) {
+		if space.Path != "" && (!filepath.IsAbs(space.Path) || strings.HasPrefix(space.Path, dir)) {
+			t.Errorf("%s is measured at %s", space.Name, space.Path)
+		}
+	}
+
+	if err := removeContents(".cache"); err == nil {
+		t.Error("removeContents cleaned a relative path")
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".cache", "important")); err != nil {
+		t.Errorf("file in the working directory was removed: %v", err)
+	}
+}
+
+func TestRemoveContents(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "a", "")
+	writeFixture(t, dir, filepath.Join("sub", "b"), "")
+
+	if err := removeContents(dir, filepath.Join(dir, "missing")); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("%d entries left", len(entries))
+	}
+}
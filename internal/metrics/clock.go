@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"syscall"
+	"time"
+)
+
+// Values from <sys/timex.h>
+const (
+	timeError  = 5      // TIME_ERROR: the clock is not synchronized
+	staUnsync  = 0x0040 // STA_UNSYNC: the clock is not synchronized
+	staNano    = 0x2000 // STA_NANO: the offset is in nanoseconds rather than microseconds
+	maxErrorUs = 16000000
+)
+
+// ClockSync describes how well the system clock is kept in step with its time source
+type ClockSync struct {
+	Synchronized   bool
+	Offset         time.Duration // Last measured offset from the time source
+	EstimatedError time.Duration
+	MaxError       time.Duration // Upper bound on the error; grows while unsynchronized
+}
+
+// GetClockSync reads the kernel's clock discipline state with adjtimex.
+// The state is kept up to date by NTP daemons such as chrony or systemd-timesyncd.
+func GetClockSync() (ClockSync, error) {
+	// A zero Modes only reads the state
+	var tx syscall.Timex
+	state, err := syscall.Adjtimex(&tx)
+	if err != nil {
+		return ClockSync{}, err
+	}
+
+	offsetUnit := time.Microsecond
+	if tx.Status&staNano != 0 {
+		offsetUnit = time.Nanosecond
+	}
+
+	clock := ClockSync{
+		Synchronized:   state != timeError && tx.Status&staUnsync == 0,
+		Offset:         time.Duration(tx.Offset) * offsetUnit,
+		EstimatedError: time.Duration(tx.Esterror) * time.Microsecond,
+		MaxError:       time.Duration(tx.Maxerror) * time.Microsecond,
+	}
+
+	// The kernel caps the maximum error at 16 s, which just means "unknown"
+	if tx.Maxerror >= maxErrorUs {
+		clock.Synchronized = false
+	}
+
+	return clock, nil
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrCommandTimeout is returned when a command is killed for running too long
+var ErrCommandTimeout = errors.New("command timed out")
+
+// maxStderr is how much of a command's standard error is kept for error messages
+const maxStderr = 64 * 1024
+
+// runCommand runs a command and returns its standard output. The command runs
+// in its own process group so that a wrapper script and everything it started
+// are killed together if it exceeds timeout. A non-zero exit status is returned
+// as an *exec.ExitError holding standard error, along with the output, which
+// some tools still fill in.
+func runCommand(timeout time.Duration, name string, args ...string) ([]byte, error) {
+	output, _, err := runCommandCapped(timeout, 0, name, args...)
+	return output, err
+}
+
+// runCommandCapped is runCommand keeping at most limit bytes of standard output,
+// or all of it if limit is 0, and reporting whether any output was dropped.
+// Output beyond the limit is still read, so the command doesn't block writing it.
+func runCommandCapped(timeout time.Duration, limit int, name string, args ...string) ([]byte, bool, error) {
+	output := &cappedBuffer{limit: limit}
+	stderr := &cappedBuffer{limit: maxStderr}
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = output
+	cmd.Stderr = stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return nil, false, err
+	}
+
+	timer := time.AfterFunc(timeout, func() {
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	})
+	err := cmd.Wait()
+	if !timer.Stop() {
+		return nil, false, fmt.Errorf("%w: %s after %s", ErrCommandTimeout, name, timeout)
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitErr.Stderr = stderr.Bytes()
+	}
+
+	return output.Bytes(), output.truncated, err
+}
+
+// cappedBuffer keeps the first limit bytes written to it, or everything if limit is 0.
+// The buffer isn't embedded, as its ReadFrom would let io.Copy bypass the limit.
+type cappedBuffer struct {
+	buffer    bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// Write keeps what fits and reports the whole of p as written
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.limit > 0 && b.buffer.Len()+len(p) > b.limit {
+		b.truncated = true
+		b.buffer.Write(p[:b.limit-b.buffer.Len()])
+		return len(p), nil
+	}
+	return b.buffer.Write(p)
+}
+
+// Bytes returns what was kept
+func (b *cappedBuffer) Bytes() []byte {
+	return b.buffer.Bytes()
+}
+
+// commandError describes why a command failed, preferring the first line it wrote to standard error
+func commandError(err error) string {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if line := strings.TrimSpace(strings.SplitN(string(exitErr.Stderr), "\n", 2)[0]); line != "" {
+			return line
+		}
+	}
+	return err.Error()
+}
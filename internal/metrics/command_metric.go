@@ -0,0 +1,237 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Defaults for command metrics that leave these settings out
+	defaultCommandInterval  = 30 * time.Second
+	defaultCommandTimeout   = 10 * time.Second
+	defaultCommandMaxOutput = 64 * 1024
+)
+
+// numberWithUnitPattern matches a number at the start of a string, followed by an optional unit
+var numberWithUnitPattern = regexp.MustCompile(`^\s*([-+]?(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?)[ \t]*(\S*)`)
+
+// CustomMetricValue is the latest reading of a user-defined metric
+type CustomMetricValue struct {
+	Label   string
+	Value   float64
+	Unit    string
+	Level   AlertLevel // Whether the value crossed one of the metric's thresholds, for metrics that have them
+	Updated time.Time  // When the value was last read; zero until the first reading
+	Error   string     // Why the latest reading failed, if it did
+}
+
+// CommandMetric is a user-defined metric read from the output of a shell command,
+// in the style of genmon or Argos. The command runs on its own interval in the
+// background, so a slow command never delays the built-in metrics.
+type CommandMetric struct {
+	Label     string
+	command   string
+	unit      string
+	interval  time.Duration
+	timeout   time.Duration
+	maxOutput int
+	pattern   *regexp.Regexp
+	jsonPath  *jsonPath
+	value     CustomMetricValue
+	mutex     sync.Mutex
+	stop      chan struct{}
+}
+
+// NewCommandMetric creates a metric from a command run with sh -c. The number is
+// taken from the first group of pattern, with the unit from its second group if
+// it has one; from jsonPath, e.g. ".queue.pending", if the command prints JSON;
+// or otherwise from the start of the output. Zero durations and sizes fall back
+// to the defaults.
+func NewCommandMetric(label, command string, interval, timeout time.Duration, maxOutput int, pattern, path, unit string) (*CommandMetric, error) {
+	if command == "" {
+		return nil, fmt.Errorf("command metric %q needs a command", label)
+	}
+	if pattern != "" && path != "" {
+		return nil, fmt.Errorf("command metric %q has both a pattern and a JSON path", label)
+	}
+
+	m := &CommandMetric{
+		Label:     label,
+		command:   command,
+		unit:      unit,
+		interval:  interval,
+		timeout:   timeout,
+		maxOutput: maxOutput,
+		value:     CustomMetricValue{Label: label, Unit: unit},
+	}
+	if m.interval <= 0 {
+		m.interval = defaultCommandInterval
+	}
+	if m.timeout <= 0 {
+		m.timeout = defaultCommandTimeout
+	}
+	if m.maxOutput <= 0 {
+		m.maxOutput = defaultCommandMaxOutput
+	}
+
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for %q: %v", label, err)
+		}
+		m.pattern = re
+	}
+	if path != "" {
+		p, err := parseJSONPath(path)
+		if err != nil {
+			return nil, fmt.Errorf("command metric %q: %v", label, err)
+		}
+		m.jsonPath = p
+	}
+
+	return m, nil
+}
+
+// Start runs the command now and then every interval in the background
+func (m *CommandMetric) Start() {
+	m.stop = make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			m.run()
+
+			select {
+			case <-ticker.C:
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops running the command. A run in progress isn't waited for, so that
+// quitting isn't held up by a slow command; it is killed when it times out.
+func (m *CommandMetric) Stop() {
+	if m.stop == nil {
+		return
+	}
+	close(m.stop)
+}
+
+// Value returns the latest reading
+func (m *CommandMetric) Value() CustomMetricValue {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.value
+}
+
+// run runs the command once and records the number it printed. A failed run keeps
+// the previous value, so the menu can show it along with the error.
+func (m *CommandMetric) run() {
+	value, unit, err := m.read()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err != nil {
+		m.value.Error = err.Error()
+		return
+	}
+
+	m.value.Value = value
+	m.value.Unit = m.unit
+	if unit != "" {
+		m.value.Unit = unit
+	}
+	m.value.Updated = time.Now()
+	m.value.Error = ""
+}
+
+// read runs the command and extracts the number and unit from its output
+func (m *CommandMetric) read() (float64, string, error) {
+	output, truncated, err := runCommandCapped(m.timeout, m.maxOutput, "sh", "-c", m.command)
+	if errors.Is(err, ErrCommandTimeout) {
+		return 0, "", fmt.Errorf("timed out after %s", m.timeout)
+	}
+	if err != nil {
+		return 0, "", errors.New(commandError(err))
+	}
+
+	switch {
+	case m.jsonPath != nil:
+		// Truncated JSON can't be decoded, so say why instead of reporting a syntax error
+		if truncated {
+			return 0, "", fmt.Errorf("output is larger than %d bytes", m.maxOutput)
+		}
+		var doc interface{}
+		if err := json.Unmarshal(output, &doc); err != nil {
+			return 0, "", fmt.Errorf("invalid JSON output: %v", err)
+		}
+		return m.jsonPath.lookupNumber(doc)
+
+	case m.pattern != nil:
+		match := m.pattern.FindSubmatch(output)
+		if match == nil {
+			return 0, "", errors.New("pattern did not match the output")
+		}
+		text := match[0]
+		if len(match) > 1 {
+			text = match[1]
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(string(text)), 64)
+		if err != nil {
+			return 0, "", fmt.Errorf("pattern matched %q, which is not a number", text)
+		}
+		unit := ""
+		if len(match) > 2 {
+			unit = strings.TrimSpace(string(match[2]))
+		}
+		return value, unit, nil
+	}
+
+	value, unit, ok := parseNumberWithUnit(string(output))
+	if !ok {
+		return 0, "", fmt.Errorf("output does not start with a number: %q", firstLine(string(output)))
+	}
+	return value, unit, nil
+}
+
+// parseNumberWithUnit parses text such as "42", "3.5 GB" or "-1e3ms"
+func parseNumberWithUnit(text string) (float64, string, bool) {
+	match := numberWithUnitPattern.FindStringSubmatch(text)
+	if match == nil {
+		return 0, "", false
+	}
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return value, match[2], true
+}
+
+// firstLine returns the first line of text, shortened for error messages
+func firstLine(text string) string {
+	line := strings.TrimSpace(strings.SplitN(strings.TrimSpace(text), "\n", 2)[0])
+	if runes := []rune(line); len(runes) > 40 {
+		line = string(runes[:40]) + "…"
+	}
+	return line
+}
+
+// GetCommandMetrics returns the latest reading of each metric, in the order given
+func GetCommandMetrics(metrics []*CommandMetric) []CustomMetricValue {
+	values := make([]CustomMetricValue, 0, len(metrics))
+	for _, m := range metrics {
+		values = append(values, m.Value())
+	}
+	return values
+}
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestFirstLine(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"single line", "  no output\n", "no output"},
+		{"several lines", "error: disk full\nat line 3\n", "error: disk full"},
+		{"long line", strings.Repeat("x", 50), strings.Repeat("x", 40) + "…"},
+		// 39 ASCII bytes then a 3-byte character: cutting at byte 40 would split it
+		{"multibyte at the cut", strings.Repeat("x", 39) + "€€€", strings.Repeat("x", 39) + "€…"},
+		{"multibyte only", strings.Repeat("ö", 45), strings.Repeat("ö", 40) + "…"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := firstLine(test.text)
+			if got != test.want {
+				t.Errorf("firstLine() = %q, want %q", got, test.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("firstLine() = %q is not valid UTF-8", got)
+			}
+		})
+	}
+}
+
+func TestCommandMetricRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		pattern string
+		path    string
+		value   float64
+		unit    string
+		error   string // Start of the expected error
+	}{
+		{name: "number", command: "echo 42", value: 42},
+		{name: "number and unit", command: "echo '3.5 GB free'", value: 3.5, unit: "GB"},
+		{name: "not a number", command: "echo ok", error: `output does not start with a number: "ok"`},
+
+		// The first group holds the value and the second, if there is one, the unit
+		{name: "pattern value and unit", command: "echo 'tests: 12 failed in 3.2s'", pattern: `in ([\d.]+)(s)`, value: 3.2, unit: "s"},
+		{name: "pattern value", command: "echo 'tests: 12 failed, 340 passed'", pattern: `(\d+) failed`, value: 12},
+		{name: "pattern unit with spaces", command: "echo 'used= 812 MiB '", pattern: `=\s*(\d+)\s*(\w+)`, value: 812, unit: "MiB"},
+		{name: "pattern without groups", command: "echo 'load 0.75'", pattern: `\d+\.\d+`, value: 0.75},
+		{name: "pattern on a later line", command: "printf 'header\\nqueue: 7\\n'", pattern: `queue: (\d+)`, value: 7},
+		{name: "pattern no match", command: "echo 'all passed'", pattern: `(\d+) failed`, error: "pattern did not match the output"},
+		{name: "pattern not a number", command: "echo 'state: draining'", pattern: `state: (\w+)`, error: `pattern matched "draining", which is not a number`},
+
+		{name: "JSON path", command: `echo '{"queue": {"pending": 9}}'`, path: ".queue.pending", value: 9},
+		{name: "JSON path unit", command: `echo '{"latency": "250 ms"}'`, path: ".latency", value: 250, unit: "ms"},
+		{name: "invalid JSON", command: "echo 'not json'", path: ".queue", error: "invalid JSON output: "},
+
+		{name: "failure", command: "echo 'connection refused' >&2; exit 1", error: "connection refused"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, err := NewCommandMetric("test", test.command, 0, 0, 0, test.pattern, test.path, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			value, unit, err := m.read()
+			if test.error != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), test.error) {
+					t.Errorf("error = %v, want %q", err, test.error)
+				}
+				return
+			}
+			if err != nil || value != test.value || unit != test.unit {
+				t.Errorf("got %v %q, %v, want %v %q", value, unit, err, test.value, test.unit)
+			}
+		})
+	}
+}
+
+func TestCommandMetricReadLimits(t *testing.T) {
+	m, err := NewCommandMetric("test", `echo '{"values": [1, 2, 3, 4, 5, 6, 7, 8]}'`, 0, 0, 16, "", ".values[0]", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := m.read(); err == nil || err.Error() != "output is larger than 16 bytes" {
+		t.Errorf("error = %v for truncated JSON", err)
+	}
+
+	m, err = NewCommandMetric("test", "sleep 10", 0, 100*time.Millisecond, 0, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := m.read(); err == nil || err.Error() != "timed out after 100ms" {
+		t.Errorf("error = %v for a hung command", err)
+	}
+}
+
+func TestCommandMetricUnit(t *testing.T) {
+	// The configured unit is used unless the output brings its own
+	for _, test := range []struct{ command, want string }{{"echo 5", "jobs"}, {"echo 5 tasks", "tasks"}} {
+		m, err := NewCommandMetric("test", test.command, 0, 0, 0, "", "", "jobs")
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.run()
+		if value := m.Value(); value.Unit != test.want || value.Value != 5 || value.Error != "" {
+			t.Errorf("%s: got %+v, want 5 %s", test.command, value, test.want)
+		}
+	}
+}
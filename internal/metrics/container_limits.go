@@ -0,0 +1,233 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+var (
+	containerMode      bool
+	containerModeMutex sync.Mutex
+)
+
+// DetectContainer reports whether this process appears to be running inside a container
+func DetectContainer() bool {
+	// Docker and Podman leave a marker file in the container's root
+	for _, marker := range []string{"/.dockerenv", "/run/.containerenv"} {
+		if _, err := os.Stat(marker); err == nil {
+			return true
+		}
+	}
+
+	// systemd-nspawn, LXC and Podman set $container for the container's init
+	return os.Getenv("container") != ""
+}
+
+// SetContainerMode makes CPU and memory usage relative to the limits of the
+// cgroup this process runs in, instead of the host's totals
+func SetContainerMode(enabled bool) {
+	containerModeMutex.Lock()
+	defer containerModeMutex.Unlock()
+
+	containerMode = enabled
+}
+
+// ContainerMode reports whether usage is relative to cgroup limits
+func ContainerMode() bool {
+	containerModeMutex.Lock()
+	defer containerModeMutex.Unlock()
+
+	return containerMode
+}
+
+// selfCgroupV1Path returns the cgroup v1 mount directory and path of this process for a controller
+func selfCgroupV1Path(controller string) (string, string) {
+	file, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", ""
+	}
+	defer file.Close()
+
+	// Lines look like "4:memory:/docker/abc" or "2:cpu,cpuacct:/docker/abc"
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		for _, c := range strings.Split(parts[1], ",") {
+			if c == controller {
+				return parts[1], parts[2]
+			}
+		}
+	}
+
+	return "", ""
+}
+
+// cgroupV1File finds a controller file for this process. Inside a container the
+// process's cgroup is usually mounted as the root of the hierarchy, so the
+// path from /proc/self/cgroup may not exist and the root is used instead.
+func cgroupV1File(controller, file string) string {
+	mount, cgroup := selfCgroupV1Path(controller)
+	if mount == "" {
+		return ""
+	}
+
+	for _, dir := range []string{mount, controller} {
+		for _, sub := range []string{cgroup, "/"} {
+			candidate := cgroupPath(path.Join(dir, sub), file)
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate
+			}
+		}
+	}
+
+	return ""
+}
+
+// selfCgroupV2 returns this process's cgroup v2 path if its files are visible
+func selfCgroupV2() (string, bool) {
+	cgroup := processCgroup(int32(os.Getpid()))
+	if cgroup == "" {
+		return "", false
+	}
+	return visibleCgroupV2(cgroup)
+}
+
+// visibleCgroupV2 finds where a cgroup from /proc/self/cgroup is mounted. As with
+// cgroupV1File, a container sharing the host's cgroup namespace sees its full path,
+// such as /docker/<id>, while only its own cgroup is mounted, as the root.
+func visibleCgroupV2(cgroup string) (string, bool) {
+	for _, candidate := range []string{cgroup, "/"} {
+		if _, err := os.Stat(cgroupPath(candidate, "cgroup.controllers")); err == nil {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// getCgroupMemory returns the memory used by this process's cgroup, excluding
+// inactive page cache, and its limit. Without a limit the host's total is used.
+func getCgroupMemory() (uint64, uint64, error) {
+	memInfo, err := mem.VirtualMemory()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var usage, limit, inactive uint64
+	if cgroup, ok := selfCgroupV2(); ok {
+		if usage, err = readCgroupUint(cgroup, "memory.current"); err != nil {
+			return 0, 0, err
+		}
+		// memory.max is "max" when unlimited, which fails to parse and leaves the limit at 0
+		limit, _ = readCgroupUint(cgroup, "memory.max")
+		if stat, err := readCgroupKeyed(cgroup, "memory.stat"); err == nil {
+			inactive = stat["inactive_file"]
+		}
+	} else {
+		usageFile := cgroupV1File("memory", "memory.usage_in_bytes")
+		if usageFile == "" {
+			return 0, 0, fmt.Errorf("no memory cgroup found")
+		}
+		if usage, err = readUintFile(usageFile); err != nil {
+			return 0, 0, err
+		}
+		limit, _ = readUintFile(cgroupV1File("memory", "memory.limit_in_bytes"))
+		if stat, err := readKeyedFile(cgroupV1File("memory", "memory.stat")); err == nil {
+			inactive = stat["total_inactive_file"]
+		}
+	}
+
+	// cgroup v1 reports "unlimited" as a huge page-aligned number
+	if limit == 0 || limit > memInfo.Total {
+		limit = memInfo.Total
+	}
+	if inactive < usage {
+		usage -= inactive
+	}
+
+	return usage, limit, nil
+}
+
+// getCgroupCPU returns the cumulative CPU time of this process's cgroup and the
+// number of CPUs its quota allows. Without a quota every CPU is available.
+func getCgroupCPU() (time.Duration, float64, error) {
+	cpus := float64(runtime.NumCPU())
+
+	if cgroup, ok := selfCgroupV2(); ok {
+		stat, err := readCgroupKeyed(cgroup, "cpu.stat")
+		if err != nil {
+			return 0, 0, err
+		}
+
+		// cpu.max is "<quota> <period>" or "max <period>"
+		if data, err := os.ReadFile(cgroupPath(cgroup, "cpu.max")); err == nil {
+			fields := strings.Fields(string(data))
+			if len(fields) == 2 && fields[0] != "max" {
+				quota, qerr := strconv.ParseFloat(fields[0], 64)
+				period, perr := strconv.ParseFloat(fields[1], 64)
+				if qerr == nil && perr == nil && period > 0 && quota/period < cpus {
+					cpus = quota / period
+				}
+			}
+		}
+
+		return time.Duration(stat["usage_usec"]) * time.Microsecond, cpus, nil
+	}
+
+	usageFile := cgroupV1File("cpuacct", "cpuacct.usage")
+	if usageFile == "" {
+		return 0, 0, fmt.Errorf("no cpuacct cgroup found")
+	}
+	usage, err := readUintFile(usageFile)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// A quota of -1 means unlimited and fails to parse as unsigned
+	quota, qerr := readUintFile(cgroupV1File("cpu", "cpu.cfs_quota_us"))
+	period, perr := readUintFile(cgroupV1File("cpu", "cpu.cfs_period_us"))
+	if qerr == nil && perr == nil && period > 0 && float64(quota)/float64(period) < cpus {
+		cpus = float64(quota) / float64(period)
+	}
+
+	return time.Duration(usage), cpus, nil
+}
+
+// getCgroupCPUUsage measures this cgroup's CPU usage over one second as a percentage of its quota
+func getCgroupCPUUsage() (float64, error) {
+	startUsage, cpus, err := getCgroupCPU()
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+
+	time.Sleep(time.Second)
+
+	endUsage, _, err := getCgroupCPU()
+	if err != nil {
+		return 0, err
+	}
+	elapsed := time.Since(start)
+
+	if endUsage < startUsage || cpus <= 0 {
+		return 0, nil
+	}
+
+	percent := float64(endUsage-startUsage) / (float64(elapsed) * cpus) * 100
+	if percent > 100 {
+		percent = 100
+	}
+
+	return percent, nil
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestVisibleCgroupV2(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "full hierarchy",
+			files: map[string]string{
+				"cgroup.controllers":            "cpu memory io\n",
+				"docker/abc/cgroup.controllers": "cpu memory io\n",
+				"docker/abc/memory.current":     "1048576\n",
+			},
+			want: "/docker/abc",
+		},
+		{
+			// --cgroupns=host, or a private mount of the container's own cgroup
+			name: "own cgroup mounted as the root",
+			files: map[string]string{
+				"cgroup.controllers": "cpu memory io\n",
+				"memory.current":     "1048576\n",
+			},
+			want: "/",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			useCgroupRoot(t, root)
+			for name, content := range test.files {
+				writeFixture(t, root, filepath.FromSlash(name), content)
+			}
+
+			cgroup, ok := visibleCgroupV2("/docker/abc")
+			if !ok || cgroup != test.want {
+				t.Fatalf("visibleCgroupV2() = %q, %v, want %q", cgroup, ok, test.want)
+			}
+			if usage, err := readCgroupUint(cgroup, "memory.current"); err != nil || usage != 1048576 {
+				t.Errorf("memory.current = %d, %v, want the container's usage", usage, err)
+			}
+		})
+	}
+}
+
+func TestVisibleCgroupV2Missing(t *testing.T) {
+	// A cgroup v1 hierarchy has no cgroup.controllers
+	root := t.TempDir()
+	useCgroupRoot(t, root)
+	writeFixture(t, root, filepath.Join("memory", "memory.usage_in_bytes"), "1048576\n")
+
+	if cgroup, ok := visibleCgroupV2("/docker/abc"); ok {
+		t.Errorf("found cgroup v2 at %q in a v1 hierarchy", cgroup)
+	}
+}
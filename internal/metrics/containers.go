@@ -0,0 +1,235 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// containerAPITimeout bounds each request to the container engine
+const containerAPITimeout = 5 * time.Second
+
+// containerSample holds the cumulative counters of a container at the last sample
+type containerSample struct {
+	cpuUsage    uint64
+	systemUsage uint64
+	onlineCPUs  int
+	rxBytes     uint64
+	txBytes     uint64
+	time        time.Time
+}
+
+var (
+	lastContainerSamples = make(map[string]containerSample)
+	containerMutex       sync.Mutex
+)
+
+// ContainerStats contains the resource usage of a running container
+type ContainerStats struct {
+	ID          string
+	Name        string
+	Image       string
+	CPUPercent  float64 // 100% = one core, as reported by "docker stats"
+	MemoryUsage uint64  // Bytes, excluding reclaimable page cache
+	MemoryLimit uint64  // Bytes, the host's memory if the container has no limit
+	RxSpeed     float64 // Network bytes per second received
+	TxSpeed     float64 // Network bytes per second sent
+}
+
+// containerSummary is an entry in the engine's container list
+type containerSummary struct {
+	ID    string   `json:"Id"`
+	Names []string `json:"Names"`
+	Image string   `json:"Image"`
+}
+
+// containerStatsResponse is the subset of the engine's stats response that is used
+type containerStatsResponse struct {
+	CPUStats struct {
+		CPUUsage struct {
+			TotalUsage uint64 `json:"total_usage"`
+		} `json:"cpu_usage"`
+		SystemUsage uint64 `json:"system_cpu_usage"`
+		OnlineCPUs  uint32 `json:"online_cpus"`
+	} `json:"cpu_stats"`
+	MemoryStats struct {
+		Usage uint64            `json:"usage"`
+		Limit uint64            `json:"limit"`
+		Stats map[string]uint64 `json:"stats"`
+	} `json:"memory_stats"`
+	Networks map[string]struct {
+		RxBytes uint64 `json:"rx_bytes"`
+		TxBytes uint64 `json:"tx_bytes"`
+	} `json:"networks"`
+}
+
+// newSocketClient returns an HTTP client that talks to a Unix socket
+func newSocketClient(socket string) *http.Client {
+	return &http.Client{
+		Timeout: containerAPITimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+}
+
+// getJSON fetches a path from the engine API and decodes the JSON response
+func getJSON(client *http.Client, path string, v interface{}) error {
+	// The host is ignored since the client always dials the socket
+	resp, err := client.Get("http://engine" + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", path, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// GetContainerStats returns the usage of every running container, ordered by CPU usage.
+// socket is the Docker-compatible API socket, e.g. /var/run/docker.sock or Podman's
+// $XDG_RUNTIME_DIR/podman/podman.sock. CPU and network rates are computed from the
+// change since the previous call, so the first call reports them as 0.
+func GetContainerStats(socket string) ([]ContainerStats, error) {
+	containerMutex.Lock()
+	defer containerMutex.Unlock()
+
+	client := newSocketClient(socket)
+	defer client.CloseIdleConnections()
+
+	var list []containerSummary
+	if err := getJSON(client, "/containers/json", &list); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no container engine socket at %s", socket)
+		}
+		return nil, err
+	}
+
+	// Fetch stats concurrently since each request can take a while on a busy engine
+	result := make([]ContainerStats, len(list))
+	samples := make([]containerSample, len(list))
+	errs := make([]error, len(list))
+
+	var wg sync.WaitGroup
+	for n := range list {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			result[n], samples[n], errs[n] = fetchContainerStats(client, list[n])
+		}(n)
+	}
+	wg.Wait()
+
+	newSamples := make(map[string]containerSample, len(list))
+	var stats []ContainerStats
+	for n, c := range list {
+		if errs[n] != nil {
+			// The container may have stopped since it was listed
+			continue
+		}
+
+		s := result[n]
+		sample := samples[n]
+		newSamples[c.ID] = sample
+
+		if last, ok := lastContainerSamples[c.ID]; ok {
+			elapsed := sample.time.Sub(last.time).Seconds()
+			if sample.cpuUsage >= last.cpuUsage && elapsed > 0 {
+				cpuDelta := float64(sample.cpuUsage - last.cpuUsage)
+				systemDelta := float64(sample.systemUsage - last.systemUsage)
+				if sample.systemUsage > last.systemUsage {
+					s.CPUPercent = cpuDelta / systemDelta * float64(sample.onlineCPUs) * 100
+				} else {
+					// Engines that do not report system usage are measured against wall time
+					s.CPUPercent = cpuDelta / 1e9 / elapsed * 100
+				}
+			}
+			if sample.rxBytes >= last.rxBytes && sample.txBytes >= last.txBytes && elapsed > 0 {
+				s.RxSpeed = float64(sample.rxBytes-last.rxBytes) / elapsed
+				s.TxSpeed = float64(sample.txBytes-last.txBytes) / elapsed
+			}
+		}
+
+		stats = append(stats, s)
+	}
+
+	// Update last values
+	lastContainerSamples = newSamples
+
+	sort.Slice(stats, func(a, b int) bool {
+		if stats[a].CPUPercent == stats[b].CPUPercent {
+			return stats[a].MemoryUsage > stats[b].MemoryUsage
+		}
+		return stats[a].CPUPercent > stats[b].CPUPercent
+	})
+
+	return stats, nil
+}
+
+// fetchContainerStats requests a single stats snapshot for a container
+func fetchContainerStats(client *http.Client, c containerSummary) (ContainerStats, containerSample, error) {
+	// one-shot skips the engine's own second sample, since deltas are computed here
+	var resp containerStatsResponse
+	if err := getJSON(client, "/containers/"+c.ID+"/stats?stream=false&one-shot=true", &resp); err != nil {
+		return ContainerStats{}, containerSample{}, err
+	}
+
+	stats := ContainerStats{
+		ID:          c.ID,
+		Name:        containerName(c),
+		Image:       c.Image,
+		MemoryUsage: resp.MemoryStats.Usage,
+		MemoryLimit: resp.MemoryStats.Limit,
+	}
+
+	// Match "docker stats" by excluding inactive page cache (cgroup v2, then v1 naming)
+	for _, key := range []string{"inactive_file", "total_inactive_file"} {
+		if inactive, ok := resp.MemoryStats.Stats[key]; ok && inactive < stats.MemoryUsage {
+			stats.MemoryUsage -= inactive
+			break
+		}
+	}
+
+	sample := containerSample{
+		cpuUsage:    resp.CPUStats.CPUUsage.TotalUsage,
+		systemUsage: resp.CPUStats.SystemUsage,
+		onlineCPUs:  int(resp.CPUStats.OnlineCPUs),
+		time:        time.Now(),
+	}
+	if sample.onlineCPUs == 0 {
+		// Older engines do not report online CPUs
+		sample.onlineCPUs = runtime.NumCPU()
+	}
+	for _, network := range resp.Networks {
+		sample.rxBytes += network.RxBytes
+		sample.txBytes += network.TxBytes
+	}
+
+	return stats, sample, nil
+}
+
+// containerName returns a container's name without the leading slash the API adds
+func containerName(c containerSummary) string {
+	if len(c.Names) > 0 {
+		return strings.TrimPrefix(c.Names[0], "/")
+	}
+	if len(c.ID) > 12 {
+		return c.ID[:12]
+	}
+	return c.ID
+}
@@ -0,0 +1,176 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeEngine serves the parts of the Docker API used by GetContainerStats
+type fakeEngine struct {
+	mutex sync.Mutex
+	list  []containerSummary
+	stats map[string]map[string]interface{} // Stats responses by container ID
+}
+
+func (e *fakeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if r.URL.Path == "/containers/json" {
+		json.NewEncoder(w).Encode(e.list)
+		return
+	}
+
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/containers/"), "/stats")
+	stats, ok := e.stats[id]
+	if !ok {
+		http.Error(w, "no such container", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(stats)
+}
+
+// setStats sets the counters reported for a container
+func (e *fakeEngine) setStats(id string, cpuUsage, systemUsage uint64, memory, inactive uint64, rx, tx uint64) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.stats[id] = map[string]interface{}{
+		"cpu_stats": map[string]interface{}{
+			"cpu_usage":        map[string]interface{}{"total_usage": cpuUsage},
+			"system_cpu_usage": systemUsage,
+			"online_cpus":      4,
+		},
+		"memory_stats": map[string]interface{}{
+			"usage": memory,
+			"limit": 8 << 30,
+			"stats": map[string]uint64{"inactive_file": inactive},
+		},
+		"networks": map[string]interface{}{
+			"eth0": map[string]uint64{"rx_bytes": rx / 2, "tx_bytes": tx / 2},
+			"eth1": map[string]uint64{"rx_bytes": rx - rx/2, "tx_bytes": tx - tx/2},
+		},
+	}
+}
+
+// startFakeEngine serves engine on a Unix socket for the rest of the test and returns its path
+func startFakeEngine(t *testing.T, engine *fakeEngine) string {
+	t.Helper()
+
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &http.Server{Handler: engine}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+
+	return socket
+}
+
+func TestGetContainerStats(t *testing.T) {
+	engine := &fakeEngine{
+		list: []containerSummary{
+			{ID: "aaaaaaaaaaaaaaaa", Names: []string{"/postgres"}, Image: "postgres:16"},
+			{ID: "bbbbbbbbbbbbbbbb", Image: "redis:7"},
+		},
+		stats: make(map[string]map[string]interface{}),
+	}
+	engine.setStats("aaaaaaaaaaaaaaaa", 1e9, 100e9, 600<<20, 100<<20, 1000, 2000)
+	engine.setStats("bbbbbbbbbbbbbbbb", 5e9, 100e9, 50<<20, 0, 0, 0)
+	socket := startFakeEngine(t, engine)
+
+	containerMutex.Lock()
+	lastContainerSamples = make(map[string]containerSample)
+	containerMutex.Unlock()
+
+	// The first call has nothing to compare against, so only memory is known
+	stats, err := GetContainerStats(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("got %d containers, want 2: %+v", len(stats), stats)
+	}
+	for _, s := range stats {
+		if s.CPUPercent != 0 || s.RxSpeed != 0 || s.TxSpeed != 0 {
+			t.Errorf("first sample of %s has rates: %+v", s.Name, s)
+		}
+	}
+
+	// Two seconds later, postgres used 2 of the 40 CPU seconds on 4 CPUs and received 2 MB
+	engine.setStats("aaaaaaaaaaaaaaaa", 3e9, 140e9, 600<<20, 100<<20, 1000+2000000, 2000+1000000)
+	engine.setStats("bbbbbbbbbbbbbbbb", 5e9, 140e9, 50<<20, 0, 0, 0)
+	containerMutex.Lock()
+	for id, sample := range lastContainerSamples {
+		sample.time = sample.time.Add(-2 * time.Second)
+		lastContainerSamples[id] = sample
+	}
+	containerMutex.Unlock()
+
+	stats, err = GetContainerStats(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 || stats[0].Name != "postgres" {
+		t.Fatalf("containers are not ordered by CPU usage: %+v", stats)
+	}
+
+	postgres := stats[0]
+	// cpu_delta / system_delta * online_cpus = 2e9 / 40e9 * 4
+	checkNear(t, "postgres CPU%", postgres.CPUPercent, 20)
+	if want := uint64(500 << 20); postgres.MemoryUsage != want {
+		t.Errorf("postgres memory = %d, want %d without inactive_file", postgres.MemoryUsage, want)
+	}
+	if postgres.Image != "postgres:16" || postgres.MemoryLimit != 8<<30 {
+		t.Errorf("postgres = %+v", postgres)
+	}
+	// Rates are measured over the time since the last sample, so allow for the time the test took
+	checkNear(t, "postgres receive speed", postgres.RxSpeed, 1000000)
+	checkNear(t, "postgres send speed", postgres.TxSpeed, 500000)
+
+	redis := stats[1]
+	if redis.Name != "bbbbbbbbbbbb" {
+		t.Errorf("unnamed container is called %q, want its short ID", redis.Name)
+	}
+	if redis.CPUPercent != 0 || redis.RxSpeed != 0 {
+		t.Errorf("idle redis has rates: %+v", redis)
+	}
+}
+
+func TestGetContainerStatsStoppedContainer(t *testing.T) {
+	// A container listed but gone by the time its stats are requested is left out
+	engine := &fakeEngine{
+		list:  []containerSummary{{ID: "gone"}, {ID: "running", Names: []string{"/web"}}},
+		stats: make(map[string]map[string]interface{}),
+	}
+	engine.setStats("running", 1, 1, 1, 0, 0, 0)
+
+	stats, err := GetContainerStats(startFakeEngine(t, engine))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].Name != "web" {
+		t.Errorf("got %+v, want only the running container", stats)
+	}
+}
+
+func TestGetContainerStatsMissingSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+
+	_, err := GetContainerStats(socket)
+	if err == nil {
+		t.Fatal("no error without an engine")
+	}
+	if want := "no container engine socket at " + socket; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
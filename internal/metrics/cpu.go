@@ -9,6 +9,10 @@ import (
 
 // GetCPUUsage returns the current CPU usage as a percentage
 func GetCPUUsage() (float64, error) {
+	if ContainerMode() {
+		return getCgroupCPUUsage()
+	}
+
 	percent, err := cpu.Percent(time.Second, false)
 	if err != nil {
 		return 0, err
@@ -0,0 +1,212 @@
+package metrics
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DirectorySize is the result of scanning a watched directory
+type DirectorySize struct {
+	Path     string
+	Size     uint64 // Disk space used, counting hard-linked files once
+	Files    int
+	Growth   int64     // Change in Size since the previous scan
+	Scanned  time.Time // When the latest scan finished; zero until the first one has
+	Scanning bool
+	Error    string // Why the directory could not be scanned, if it couldn't
+}
+
+// DirectoryScanner periodically computes the size of a set of directories in the background
+type DirectoryScanner struct {
+	paths    []string
+	interval time.Duration
+	results  map[string]DirectorySize
+	mutex    sync.Mutex
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewDirectoryScanner creates a scanner for paths, rescanning every interval.
+// A leading "~" in a path refers to the user's home directory.
+func NewDirectoryScanner(paths []string, interval time.Duration) *DirectoryScanner {
+	s := &DirectoryScanner{
+		interval: interval,
+		results:  make(map[string]DirectorySize),
+	}
+	for _, p := range paths {
+		p = ExpandHome(p)
+		s.paths = append(s.paths, p)
+		s.results[p] = DirectorySize{Path: p}
+	}
+	return s
+}
+
+// Start begins scanning in the background
+func (s *DirectoryScanner) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+		for {
+			for _, p := range s.paths {
+				s.scan(ctx, p)
+				if ctx.Err() != nil {
+					return
+				}
+			}
+
+			select {
+			case <-time.After(s.interval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop cancels any scan in progress and waits for the scanner to finish
+func (s *DirectoryScanner) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+}
+
+// Results returns the latest size of each directory, in the order they were configured
+func (s *DirectoryScanner) Results() []DirectorySize {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	results := make([]DirectorySize, 0, len(s.paths))
+	for _, p := range s.paths {
+		results = append(results, s.results[p])
+	}
+	return results
+}
+
+// scan measures one directory and records the result unless the scan was cancelled
+func (s *DirectoryScanner) scan(ctx context.Context, path string) {
+	s.mutex.Lock()
+	result := s.results[path]
+	result.Scanning = true
+	s.results[path] = result
+	s.mutex.Unlock()
+
+	size, files, err := directorySize(ctx, path)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	result.Scanning = false
+	if ctx.Err() != nil {
+		s.results[path] = result
+		return
+	}
+	if err != nil {
+		result.Error = err.Error()
+		s.results[path] = result
+		return
+	}
+
+	if !result.Scanned.IsZero() {
+		result.Growth = int64(size) - int64(result.Size)
+	}
+	result.Size = size
+	result.Files = files
+	result.Scanned = time.Now()
+	result.Error = ""
+	s.results[path] = result
+}
+
+// directorySize returns the disk space used by the files under root, like du -sx.
+// Symbolic links below root are not followed, other filesystems mounted below
+// root are not entered and unreadable subdirectories are skipped. An unreadable
+// root is an error, rather than an empty directory.
+func directorySize(ctx context.Context, root string) (uint64, int, error) {
+	// A root that is itself a link, such as a relocated cache, is measured at its target
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return 0, 0, err
+	}
+	info, err := os.Lstat(root)
+	if err != nil {
+		return 0, 0, err
+	}
+	rootStat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, &fs.PathError{Op: "stat", Path: root, Err: syscall.ENOTSUP}
+	}
+
+	var size uint64
+	var files int
+	seen := make(map[uint64]bool) // Inodes of hard-linked files already counted
+
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			if path == root {
+				return err
+			}
+			// Skip what we cannot read rather than failing the whole scan
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return nil
+		}
+
+		if uint64(stat.Dev) != uint64(rootStat.Dev) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if stat.Nlink > 1 && !d.IsDir() {
+			if seen[uint64(stat.Ino)] {
+				return nil
+			}
+			seen[uint64(stat.Ino)] = true
+		}
+
+		// Blocks are always 512 bytes, and unlike the length account for sparse files
+		size += uint64(stat.Blocks) * 512
+		if d.Type().IsRegular() {
+			files++
+		}
+		return nil
+	})
+
+	return size, files, err
+}
+
+// ExpandHome replaces a leading "~" in a path with the user's home directory
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
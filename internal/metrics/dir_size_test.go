@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDirectorySizeSymlinkedRoot(t *testing.T) {
+	// A relocated cache: ~/.cache is a link to a directory on another disk
+	dir := t.TempDir()
+	target := filepath.Join(dir, "data", "cache")
+	writeFixture(t, target, "a", strings.Repeat("x", 64*1024))
+	writeFixture(t, target, filepath.Join("sub", "b"), strings.Repeat("x", 64*1024))
+	link := filepath.Join(dir, "cache")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	want, wantFiles, err := directorySize(context.Background(), target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size, files, err := directorySize(context.Background(), link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != want || files != wantFiles || files != 2 {
+		t.Errorf("link measures %d bytes in %d files, target %d bytes in %d files", size, files, want, wantFiles)
+	}
+}
+
+func TestDirectorySizeUnreadableRoot(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read any directory")
+	}
+
+	// Like /var/lib/docker for a normal user
+	root := filepath.Join(t.TempDir(), "docker")
+	writeFixture(t, root, "overlay2", "")
+	t.Cleanup(func() { os.Chmod(root, 0755) })
+	if err := os.Chmod(root, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := directorySize(context.Background(), root); !os.IsPermission(err) {
+		t.Errorf("error = %v, want permission denied", err)
+	}
+}
+
+func TestDirectorySizeMissingRoot(t *testing.T) {
+	if _, _, err := directorySize(context.Background(), filepath.Join(t.TempDir(), "missing")); !os.IsNotExist(err) {
+		t.Errorf("error = %v, want not found", err)
+	}
+}
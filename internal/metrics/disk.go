@@ -8,7 +8,8 @@ import (
 	"github.com/shirou/gopsutil/v3/disk"
 )
 
-// GetDiskUsage returns the current disk usage as a percentage
+// GetDiskUsage returns the current disk usage as a percentage.
+// Inside a container "/" is the container's own root filesystem.
 func GetDiskUsage() (float64, error) {
 	usage, err := disk.Usage("/")
 	if err != nil {
@@ -20,6 +21,15 @@ func GetDiskUsage() (float64, error) {
 
 // GetDiskUsageDetails returns detailed disk usage information
 func GetDiskUsageDetails() (string, error) {
+	// Minimal container images often lack df, and the container's root is what matters there
+	if ContainerMode() {
+		usage, err := disk.Usage("/")
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s Used, %s Total", FormatBytes(usage.Used), FormatBytes(usage.Total)), nil
+	}
+
 	cmd := exec.Command("df", "-h", "/")
 	output, err := cmd.Output()
 	if err != nil {
@@ -0,0 +1,286 @@
+package metrics
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// DiskEntry is a file or directory found by a disk usage analysis
+type DiskEntry struct {
+	Path string
+	Size uint64 // Disk space used; for directories, including everything below them
+}
+
+// DiskUsageReport is the result of a disk usage analysis
+type DiskUsageReport struct {
+	Root        string
+	Size        uint64
+	Files       int64
+	Directories []DiskEntry // Largest directories below the root, largest first
+	LargeFiles  []DiskEntry // Largest files, largest first
+	Elapsed     time.Duration
+}
+
+// DiskAnalyzer scans a directory tree in the background to find what uses the most space
+type DiskAnalyzer struct {
+	root   string
+	limit  int
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	sem    chan struct{} // Limits the number of directories read concurrently
+	dev    uint64
+
+	files int64  // Updated atomically while scanning
+	bytes uint64 // Updated atomically while scanning
+
+	mutex      sync.Mutex
+	dirs       []DiskEntry
+	largeFiles []DiskEntry
+	seen       map[uint64]bool // Inodes of hard-linked files already counted
+
+	report DiskUsageReport
+	err    error
+}
+
+// StartDiskAnalysis begins scanning root in the background, keeping the limit
+// largest directories and files. Like the directory watches, the scan stays on
+// root's filesystem and does not follow symbolic links.
+func StartDiskAnalysis(root string, limit int) *DiskAnalyzer {
+	ctx, cancel := context.WithCancel(context.Background())
+	a := &DiskAnalyzer{
+		root:   root,
+		limit:  limit,
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		sem:    make(chan struct{}, 4*runtime.NumCPU()),
+		seen:   make(map[uint64]bool),
+	}
+
+	go a.run()
+	return a
+}
+
+// Root returns the directory being analyzed
+func (a *DiskAnalyzer) Root() string {
+	return a.root
+}
+
+// Progress returns the number of files and bytes counted so far
+func (a *DiskAnalyzer) Progress() (int64, uint64) {
+	return atomic.LoadInt64(&a.files), atomic.LoadUint64(&a.bytes)
+}
+
+// Cancel stops the scan; Result then returns context.Canceled
+func (a *DiskAnalyzer) Cancel() {
+	a.cancel()
+}
+
+// Done is closed when the scan has finished or been cancelled
+func (a *DiskAnalyzer) Done() <-chan struct{} {
+	return a.done
+}
+
+// Result returns the report once Done is closed
+func (a *DiskAnalyzer) Result() (DiskUsageReport, error) {
+	<-a.done
+	return a.report, a.err
+}
+
+// run scans the tree and builds the report
+func (a *DiskAnalyzer) run() {
+	defer close(a.done)
+	start := time.Now()
+
+	info, err := os.Lstat(a.root)
+	if err != nil {
+		a.err = err
+		return
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || !info.IsDir() {
+		a.err = &os.PathError{Op: "analyze", Path: a.root, Err: syscall.ENOTDIR}
+		return
+	}
+	a.dev = uint64(stat.Dev)
+
+	size := a.walk(a.root, uint64(stat.Blocks)*512)
+	if err := a.ctx.Err(); err != nil {
+		a.err = err
+		return
+	}
+
+	a.report = DiskUsageReport{
+		Root:        a.root,
+		Size:        size,
+		Files:       atomic.LoadInt64(&a.files),
+		Directories: topEntries(collapseChains(a.dirs), a.limit),
+		LargeFiles:  topEntries(a.largeFiles, a.limit),
+		Elapsed:     time.Since(start),
+	}
+}
+
+// walk returns the disk space used by dir and everything below it. Subdirectories
+// are walked in new goroutines while the concurrency limit allows, and inline otherwise.
+func (a *DiskAnalyzer) walk(dir string, ownSize uint64) uint64 {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		// Unreadable directories are counted as empty
+		return ownSize
+	}
+
+	var wg sync.WaitGroup
+	total := ownSize
+	var subdirs uint64
+
+	for _, entry := range entries {
+		if a.ctx.Err() != nil {
+			break
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok || uint64(stat.Dev) != a.dev {
+			// Don't cross into other filesystems mounted below the root
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		size := uint64(stat.Blocks) * 512
+
+		if entry.IsDir() {
+			select {
+			case a.sem <- struct{}{}:
+				wg.Add(1)
+				go func(path string, size uint64) {
+					defer wg.Done()
+					atomic.AddUint64(&subdirs, a.walk(path, size))
+					<-a.sem
+				}(path, size)
+			default:
+				atomic.AddUint64(&subdirs, a.walk(path, size))
+			}
+			continue
+		}
+
+		if stat.Nlink > 1 && !a.firstLink(uint64(stat.Ino)) {
+			continue
+		}
+
+		total += size
+		atomic.AddInt64(&a.files, 1)
+		atomic.AddUint64(&a.bytes, size)
+		if entry.Type().IsRegular() {
+			a.record(&a.largeFiles, DiskEntry{Path: path, Size: size})
+		}
+	}
+
+	wg.Wait()
+	total += atomic.LoadUint64(&subdirs)
+
+	if dir != a.root {
+		a.record(&a.dirs, DiskEntry{Path: dir, Size: total})
+	}
+	return total
+}
+
+// firstLink reports whether a hard-linked inode is seen for the first time
+func (a *DiskAnalyzer) firstLink(ino uint64) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.seen[ino] {
+		return false
+	}
+	a.seen[ino] = true
+	return true
+}
+
+// record adds an entry to a list, trimming the list to the largest entries so
+// that scanning a whole filesystem doesn't keep every path in memory
+func (a *DiskAnalyzer) record(list *[]DiskEntry, entry DiskEntry) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// Extra entries are kept so that collapseChains still has enough left
+	*list = append(*list, entry)
+	if len(*list) > 8*a.limit {
+		*list = topEntries(*list, 4*a.limit)
+	}
+}
+
+// collapseChains drops directories that hold little besides a single subdirectory,
+// so that /home, /home/user and /home/user/.cache are reported as just the cache
+func collapseChains(dirs []DiskEntry) []DiskEntry {
+	var collapsed []DiskEntry
+	for _, dir := range dirs {
+		prefix := dir.Path + string(filepath.Separator)
+		dominated := false
+		for _, other := range dirs {
+			if strings.HasPrefix(other.Path, prefix) && other.Size >= dir.Size*9/10 {
+				dominated = true
+				break
+			}
+		}
+		if !dominated {
+			collapsed = append(collapsed, dir)
+		}
+	}
+	return collapsed
+}
+
+// topEntries returns the limit largest entries, largest first
+func topEntries(entries []DiskEntry, limit int) []DiskEntry {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Size > entries[j].Size
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries
+}
+
+// GetMountPoints returns the mount points of filesystems on block devices, such as / and /home
+func GetMountPoints() ([]string, error) {
+	data, err := os.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	return parseMountPoints(string(data)), nil
+}
+
+// parseMountPoints returns the sorted mount points of block device filesystems in mountinfo
+func parseMountPoints(mountinfo string) []string {
+	var mounts []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(mountinfo, "\n") {
+		fields := strings.Fields(line)
+		for n, field := range fields {
+			if field != "-" || n < 5 || n+2 >= len(fields) {
+				continue
+			}
+			mountPoint := unescapeMountPath(fields[4])
+			fsType, device := fields[n+1], fields[n+2]
+			if strings.HasPrefix(device, "/dev/") && !readOnlyFSTypes[fsType] && !seen[mountPoint] {
+				seen[mountPoint] = true
+				mounts = append(mounts, mountPoint)
+			}
+			break
+		}
+	}
+
+	sort.Strings(mounts)
+	return mounts
+}
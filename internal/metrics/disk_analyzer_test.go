@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollapseChains(t *testing.T) {
+	tests := []struct {
+		name string
+		dirs []DiskEntry
+		want []DiskEntry
+	}{
+		{
+			name: "chain",
+			dirs: []DiskEntry{{"/home", 100}, {"/home/user", 95}, {"/home/user/.cache", 90}},
+			want: []DiskEntry{{"/home/user/.cache", 90}},
+		},
+		{
+			// Nothing below /var holds 90% of it
+			name: "spread out",
+			dirs: []DiskEntry{{"/var", 100}, {"/var/lib", 50}, {"/var/log", 40}},
+			want: []DiskEntry{{"/var", 100}, {"/var/lib", 50}, {"/var/log", 40}},
+		},
+		{
+			name: "chain ending in a spread out directory",
+			dirs: []DiskEntry{{"/srv", 1000}, {"/srv/data", 990}, {"/srv/data/a", 500}, {"/srv/data/b", 490}},
+			want: []DiskEntry{{"/srv/data", 990}, {"/srv/data/a", 500}, {"/srv/data/b", 490}},
+		},
+		{
+			// A sibling with a longer name is not a subdirectory
+			name: "shared prefix",
+			dirs: []DiskEntry{{"/data", 100}, {"/database", 95}},
+			want: []DiskEntry{{"/data", 100}, {"/database", 95}},
+		},
+		{
+			name: "just under the threshold",
+			dirs: []DiskEntry{{"/opt", 1000}, {"/opt/app", 899}},
+			want: []DiskEntry{{"/opt", 1000}, {"/opt/app", 899}},
+		},
+		{name: "empty"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := collapseChains(test.dirs); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRecordTrimsList(t *testing.T) {
+	a := &DiskAnalyzer{limit: 2}
+	var list []DiskEntry
+
+	// Up to 8 times the limit is kept as it is
+	for n := 1; n <= 16; n++ {
+		a.record(&list, DiskEntry{Path: "/f" + itoa(uint64(n)), Size: uint64(n)})
+	}
+	if len(list) != 16 {
+		t.Fatalf("%d entries kept, want 16", len(list))
+	}
+
+	// One more trims it to the 4 times the limit largest, largest first
+	a.record(&list, DiskEntry{Path: "/f17", Size: 17})
+	var sizes []uint64
+	for _, entry := range list {
+		sizes = append(sizes, entry.Size)
+	}
+	if want := []uint64{17, 16, 15, 14, 13, 12, 11, 10}; !reflect.DeepEqual(sizes, want) {
+		t.Errorf("kept sizes %v, want %v", sizes, want)
+	}
+
+	// The result keeps the limit largest entries
+	if top := topEntries(list, a.limit); len(top) != 2 || top[0].Path != "/f17" || top[1].Path != "/f16" {
+		t.Errorf("top entries = %v", top)
+	}
+}
+
+func TestParseMountPoints(t *testing.T) {
+	mountinfo := `22 1 259:2 / / rw,relatime shared:1 - ext4 /dev/nvme0n1p2 rw,errors=remount-ro
+23 22 259:3 / /home rw,relatime shared:2 - ext4 /dev/nvme0n1p3 rw
+24 22 259:1 / /boot/efi rw,relatime shared:3 - vfat /dev/nvme0n1p1 rw,fmask=0077
+25 23 259:3 /srv /srv rw,relatime shared:2 - ext4 /dev/nvme0n1p3 rw
+26 22 8:33 / /media/user/My\040Disk rw,nosuid,nodev shared:31 - exfat /dev/sdc1 rw
+27 22 7:3 / /snap/core22/1380 ro,nodev,relatime shared:33 - squashfs /dev/loop3 ro
+28 22 0:26 / /run rw,nosuid,nodev shared:5 - tmpfs tmpfs rw,size=1628084k
+29 22 0:27 / /proc rw,nosuid,nodev,noexec shared:12 - proc proc rw
+30 23 259:3 / /home rw,relatime shared:2 - ext4 /dev/nvme0n1p3 rw
+`
+
+	want := []string{"/", "/boot/efi", "/home", "/media/user/My Disk", "/srv"}
+	if got := parseMountPoints(mountinfo); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
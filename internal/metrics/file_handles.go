@@ -0,0 +1,149 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// FileHandleUsage contains system-wide file handle and inotify usage
+type FileHandleUsage struct {
+	OpenFiles         uint64 // Allocated file handles, from /proc/sys/fs/file-nr
+	MaxFiles          uint64 // System-wide limit on file handles
+	InotifyWatches    int    // Watches held by the current user's processes
+	InotifyInstances  int    // inotify instances held by the current user's processes
+	MaxInotifyWatches uint64 // Per-user limit, from max_user_watches
+	TopProcesses      []ProcessFDs
+}
+
+// ProcessFDs is the number of open file descriptors of a process
+type ProcessFDs struct {
+	PID  int32
+	Name string
+	FDs  int
+}
+
+// InotifyPercent returns inotify watch usage as a percentage of the per-user limit
+func (u FileHandleUsage) InotifyPercent() float64 {
+	if u.MaxInotifyWatches == 0 {
+		return 0
+	}
+	return float64(u.InotifyWatches) / float64(u.MaxInotifyWatches) * 100
+}
+
+// OpenFilesPercent returns allocated file handles as a percentage of the system-wide limit
+func (u FileHandleUsage) OpenFilesPercent() float64 {
+	if u.MaxFiles == 0 {
+		return 0
+	}
+	return float64(u.OpenFiles) / float64(u.MaxFiles) * 100
+}
+
+// GetFileHandleUsage returns file handle and inotify usage, along with up to limit
+// processes ordered by open file descriptors. The inotify limit applies per user, so
+// only watches held by the current user's processes are counted.
+func GetFileHandleUsage(limit int) (FileHandleUsage, error) {
+	usage := FileHandleUsage{}
+
+	// file-nr holds "allocated unused max"
+	data, err := os.ReadFile("/proc/sys/fs/file-nr")
+	if err != nil {
+		return usage, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 3 {
+		return usage, fmt.Errorf("unexpected file-nr format")
+	}
+	usage.OpenFiles, _ = strconv.ParseUint(fields[0], 10, 64)
+	usage.MaxFiles, _ = strconv.ParseUint(fields[2], 10, 64)
+
+	usage.MaxInotifyWatches, _ = readUintFile("/proc/sys/fs/inotify/max_user_watches")
+
+	pids, err := process.Pids()
+	if err != nil {
+		return usage, err
+	}
+
+	uid := uint32(os.Getuid())
+	for _, pid := range pids {
+		fdDir := fmt.Sprintf("/proc/%d/fd", pid)
+
+		// Other users' descriptors are not readable unless running as root
+		entries, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+
+		if name, err := readProcName(pid); err == nil {
+			usage.TopProcesses = append(usage.TopProcesses, ProcessFDs{PID: pid, Name: name, FDs: len(entries)})
+		}
+
+		if !ownedBy(pid, uid) {
+			continue
+		}
+
+		for _, entry := range entries {
+			target, err := os.Readlink(filepath.Join(fdDir, entry.Name()))
+			if err != nil || target != "anon_inode:inotify" {
+				continue
+			}
+			usage.InotifyInstances++
+			usage.InotifyWatches += countInotifyWatches(pid, entry.Name())
+		}
+	}
+
+	sort.Slice(usage.TopProcesses, func(a, b int) bool {
+		return usage.TopProcesses[a].FDs > usage.TopProcesses[b].FDs
+	})
+
+	if limit > 0 && len(usage.TopProcesses) > limit {
+		usage.TopProcesses = usage.TopProcesses[:limit]
+	}
+
+	return usage, nil
+}
+
+// readProcName returns the command name of a process from /proc/[pid]/comm
+func readProcName(pid int32) (string, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// ownedBy reports whether a process belongs to a user
+func ownedBy(pid int32, uid uint32) bool {
+	info, err := os.Stat(fmt.Sprintf("/proc/%d", pid))
+	if err != nil {
+		return false
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && stat.Uid == uid
+}
+
+// countInotifyWatches counts the watches of an inotify descriptor from its fdinfo
+func countInotifyWatches(pid int32, fd string) int {
+	file, err := os.Open(fmt.Sprintf("/proc/%d/fdinfo/%s", pid, fd))
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "inotify wd:") {
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,200 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Defaults for HTTP metrics that leave these settings out
+	defaultHTTPInterval = 30 * time.Second
+	defaultHTTPTimeout  = 5 * time.Second
+
+	// maxHTTPResponse is the largest response body that is decoded
+	maxHTTPResponse = 1024 * 1024
+)
+
+// HTTPMetricOptions configures where an HTTP metric is read from
+type HTTPMetricOptions struct {
+	URL        string
+	Headers    map[string]string
+	Token      string // Sent as a bearer token
+	TokenFile  string // File holding the token, read on every request so it can be rotated
+	Interval   time.Duration
+	Timeout    time.Duration
+	JSONPath   string // e.g. ".queue.pending"
+	Unit       string
+	Thresholds Thresholds
+}
+
+// HTTPMetric is a user-defined metric read from a JSON status endpoint. Like a
+// command metric, the endpoint is polled on its own interval in the background.
+type HTTPMetric struct {
+	Label   string
+	opts    HTTPMetricOptions
+	path    *jsonPath
+	client  *http.Client
+	value   CustomMetricValue
+	mutex   sync.Mutex
+	cancel  context.CancelFunc
+	polling context.Context
+}
+
+// NewHTTPMetric creates a metric polling opts.URL. Zero durations fall back to the defaults.
+func NewHTTPMetric(label string, opts HTTPMetricOptions) (*HTTPMetric, error) {
+	u, err := url.Parse(opts.URL)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP metric %q: %v", label, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("HTTP metric %q needs an http or https URL", label)
+	}
+	if opts.Token != "" && opts.TokenFile != "" {
+		return nil, fmt.Errorf("HTTP metric %q has both a token and a token file", label)
+	}
+
+	path, err := parseJSONPath(opts.JSONPath)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP metric %q: %v", label, err)
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultHTTPInterval
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultHTTPTimeout
+	}
+	opts.TokenFile = ExpandHome(opts.TokenFile)
+
+	return &HTTPMetric{
+		Label:  label,
+		opts:   opts,
+		path:   path,
+		client: &http.Client{Timeout: opts.Timeout},
+		value:  CustomMetricValue{Label: label, Unit: opts.Unit},
+	}, nil
+}
+
+// Start polls the endpoint now and then every interval in the background
+func (m *HTTPMetric) Start() {
+	m.polling, m.cancel = context.WithCancel(context.Background())
+
+	go func() {
+		ticker := time.NewTicker(m.opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			m.poll()
+
+			select {
+			case <-ticker.C:
+			case <-m.polling.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops polling, cancelling a request in progress
+func (m *HTTPMetric) Stop() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+}
+
+// Value returns the latest reading
+func (m *HTTPMetric) Value() CustomMetricValue {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.value
+}
+
+// poll reads the endpoint once. A failed request keeps the previous value, so
+// the menu can show it along with the error.
+func (m *HTTPMetric) poll() {
+	value, unit, err := m.fetch()
+	if m.polling.Err() != nil {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err != nil {
+		m.value.Error = err.Error()
+		return
+	}
+
+	m.value.Value = value
+	m.value.Unit = m.opts.Unit
+	if unit != "" {
+		m.value.Unit = unit
+	}
+	m.value.Level = m.opts.Thresholds.Level(value)
+	m.value.Updated = time.Now()
+	m.value.Error = ""
+}
+
+// fetch requests the endpoint and extracts the number from its response
+func (m *HTTPMetric) fetch() (float64, string, error) {
+	req, err := http.NewRequestWithContext(m.polling, http.MethodGet, m.opts.URL, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	for name, value := range m.opts.Headers {
+		req.Header.Set(name, value)
+	}
+
+	token := m.opts.Token
+	if m.opts.TokenFile != "" {
+		data, err := os.ReadFile(m.opts.TokenFile)
+		if err != nil {
+			return 0, "", fmt.Errorf("reading token: %v", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			return 0, "", fmt.Errorf("timed out after %s", m.opts.Timeout)
+		}
+		// The URL is already shown in the menu, so leave it out of the error
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, "", fmt.Errorf("server returned %s", resp.Status)
+	}
+
+	var doc interface{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxHTTPResponse)).Decode(&doc); err != nil {
+		return 0, "", fmt.Errorf("invalid JSON response: %v", err)
+	}
+	return m.path.lookupNumber(doc)
+}
+
+// GetHTTPMetrics returns the latest reading of each metric, in the order given
+func GetHTTPMetrics(metrics []*HTTPMetric) []CustomMetricValue {
+	values := make([]CustomMetricValue, 0, len(metrics))
+	for _, m := range metrics {
+		values = append(values, m.Value())
+	}
+	return values
+}
@@ -0,0 +1,246 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// pollOnce reads an HTTP metric once, without starting its background polling
+func pollOnce(t *testing.T, m *HTTPMetric) CustomMetricValue {
+	t.Helper()
+
+	if m.polling == nil {
+		m.polling, m.cancel = context.WithCancel(context.Background())
+		t.Cleanup(m.Stop)
+	}
+	m.poll()
+	return m.Value()
+}
+
+// newTestHTTPMetric creates an HTTP metric reading from server
+func newTestHTTPMetric(t *testing.T, server *httptest.Server, opts HTTPMetricOptions) *HTTPMetric {
+	t.Helper()
+
+	opts.URL = server.URL + "/status"
+	m, err := NewHTTPMetric("queue", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// serveJSON starts a server answering every request with body
+func serveJSON(t *testing.T, body string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPMetricJSONPath(t *testing.T) {
+	server := serveJSON(t, `{"queue": {"pending": 12, "latency": "250 ms"}, "workers": [{"load": 0.5}, {"load": 1.5}]}`)
+
+	tests := []struct {
+		path  string
+		value float64
+		unit  string
+		error string
+	}{
+		{path: ".queue.pending", value: 12, unit: "jobs"},
+		{path: ".workers[1].load", value: 1.5, unit: "jobs"},
+		// A number in a string brings its own unit
+		{path: ".queue.latency", value: 250, unit: "ms"},
+		{path: ".queue.missing", error: ".queue.missing: no such key"},
+		{path: ".workers[2].load", error: ".workers[2]: index out of range"},
+		{path: ".queue", error: ".queue: not a number"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			value := pollOnce(t, newTestHTTPMetric(t, server, HTTPMetricOptions{JSONPath: test.path, Unit: "jobs"}))
+			if test.error != "" {
+				if !strings.HasPrefix(value.Error, test.error) || !value.Updated.IsZero() {
+					t.Errorf("got %+v, want error %q", value, test.error)
+				}
+				return
+			}
+			if value.Error != "" || value.Value != test.value || value.Unit != test.unit || value.Updated.IsZero() {
+				t.Errorf("got %+v, want %v %s", value, test.value, test.unit)
+			}
+		})
+	}
+}
+
+func TestHTTPMetricRequest(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		w.Write([]byte(`{"pending": 1}`))
+	}))
+	t.Cleanup(server.Close)
+
+	m := newTestHTTPMetric(t, server, HTTPMetricOptions{
+		JSONPath: ".pending",
+		Token:    "s3cret",
+		Headers:  map[string]string{"X-Api-Version": "2", "Accept": "application/vnd.status+json"},
+	})
+	if value := pollOnce(t, m); value.Error != "" {
+		t.Fatal(value.Error)
+	}
+
+	received := <-requests
+	if received.URL.Path != "/status" || received.Method != http.MethodGet {
+		t.Errorf("request was %s %s", received.Method, received.URL.Path)
+	}
+	if got := received.Header.Get("Authorization"); got != "Bearer s3cret" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if got := received.Header.Get("X-Api-Version"); got != "2" {
+		t.Errorf("X-Api-Version = %q", got)
+	}
+	// Configured headers take precedence over the defaults
+	if got := received.Header.Get("Accept"); got != "application/vnd.status+json" {
+		t.Errorf("Accept = %q", got)
+	}
+}
+
+func TestHTTPMetricTokenFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer second" {
+			http.Error(w, "bad token", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"pending": 7}`))
+	}))
+	t.Cleanup(server.Close)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	m := newTestHTTPMetric(t, server, HTTPMetricOptions{JSONPath: ".pending", TokenFile: tokenFile})
+
+	if value := pollOnce(t, m); !strings.HasPrefix(value.Error, "reading token: ") {
+		t.Errorf("error = %q without a token file", value.Error)
+	}
+
+	writeFixture(t, filepath.Dir(tokenFile), "token", "first\n")
+	if value := pollOnce(t, m); value.Error != "server returned 401 Unauthorized" {
+		t.Errorf("error = %q with the old token", value.Error)
+	}
+
+	// The token is rotated without restarting
+	if err := os.WriteFile(tokenFile, []byte("second\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if value := pollOnce(t, m); value.Error != "" || value.Value != 7 {
+		t.Errorf("got %+v with the new token", value)
+	}
+}
+
+func TestHTTPMetricErrorKeepsValue(t *testing.T) {
+	var status int32 = http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if code := int(atomic.LoadInt32(&status)); code != http.StatusOK {
+			http.Error(w, "unavailable", code)
+			return
+		}
+		w.Write([]byte(`{"pending": 5}`))
+	}))
+	t.Cleanup(server.Close)
+
+	m := newTestHTTPMetric(t, server, HTTPMetricOptions{JSONPath: ".pending"})
+	before := pollOnce(t, m)
+
+	for _, code := range []int{http.StatusNotFound, http.StatusServiceUnavailable, http.StatusNotModified} {
+		atomic.StoreInt32(&status, int32(code))
+		value := pollOnce(t, m)
+		if want := fmt.Sprintf("server returned %d %s", code, http.StatusText(code)); value.Error != want {
+			t.Errorf("error = %q, want %q", value.Error, want)
+		}
+		// The last value is still shown, marked as stale
+		if value.Value != 5 || !value.Updated.Equal(before.Updated) {
+			t.Errorf("got %+v after a failed request, want the previous value", value)
+		}
+	}
+
+	atomic.StoreInt32(&status, http.StatusOK)
+	if value := pollOnce(t, m); value.Error != "" {
+		t.Errorf("error = %q after the server recovered", value.Error)
+	}
+}
+
+func TestHTTPMetricInvalidJSON(t *testing.T) {
+	server := serveJSON(t, `<html>Bad gateway</html>`)
+
+	value := pollOnce(t, newTestHTTPMetric(t, server, HTTPMetricOptions{JSONPath: ".pending"}))
+	if !strings.HasPrefix(value.Error, "invalid JSON response: ") {
+		t.Errorf("error = %q", value.Error)
+	}
+}
+
+func TestHTTPMetricTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	m := newTestHTTPMetric(t, server, HTTPMetricOptions{JSONPath: ".pending", Timeout: 100 * time.Millisecond})
+	start := time.Now()
+	value := pollOnce(t, m)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("request took %s", elapsed)
+	}
+	if value.Error != "timed out after 100ms" {
+		t.Errorf("error = %q", value.Error)
+	}
+}
+
+func TestHTTPMetricThresholds(t *testing.T) {
+	level := func(v float64) *float64 { return &v }
+	thresholds := Thresholds{
+		WarnAbove:     level(100),
+		CriticalAbove: level(500),
+		WarnBelow:     level(10),
+		CriticalBelow: level(1),
+	}
+
+	tests := []struct {
+		body string
+		want AlertLevel
+	}{
+		{`{"pending": 50}`, LevelOK},
+		{`{"pending": 100}`, LevelOK},
+		{`{"pending": 101}`, LevelWarning},
+		{`{"pending": 501}`, LevelCritical},
+		{`{"pending": 10}`, LevelOK},
+		{`{"pending": 9}`, LevelWarning},
+		{`{"pending": 0.5}`, LevelCritical},
+	}
+
+	for _, test := range tests {
+		t.Run(test.body, func(t *testing.T) {
+			server := serveJSON(t, test.body)
+			value := pollOnce(t, newTestHTTPMetric(t, server, HTTPMetricOptions{JSONPath: ".pending", Thresholds: thresholds}))
+			if value.Error != "" || value.Level != test.want {
+				t.Errorf("got %+v, want level %d", value, test.want)
+			}
+		})
+	}
+
+	// Without thresholds, no value is flagged
+	server := serveJSON(t, `{"pending": 1e9}`)
+	if value := pollOnce(t, newTestHTTPMetric(t, server, HTTPMetricOptions{JSONPath: ".pending"})); value.Level != LevelOK {
+		t.Errorf("level = %d without thresholds", value.Level)
+	}
+}
@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a parsed path into a decoded JSON document, such as ".queue.pending" or ".workers[0].load"
+type jsonPath struct {
+	source string
+	steps  []jsonPathStep
+}
+
+// jsonPathStep is either an object key or an array index
+type jsonPathStep struct {
+	key   string
+	index int // Used when key is empty
+}
+
+// parseJSONPath parses a jq-style path made of ".key" and "[index]" steps
+func parseJSONPath(path string) (*jsonPath, error) {
+	p := &jsonPath{source: path}
+	rest := strings.TrimSpace(path)
+	if rest == "" || rest == "." {
+		return p, nil
+	}
+
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid JSON path %q: empty key", path)
+			}
+			p.steps = append(p.steps, jsonPathStep{key: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSON path %q: missing ]", path)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid JSON path %q: bad index %q", path, rest[1:end])
+			}
+			p.steps = append(p.steps, jsonPathStep{index: index})
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid JSON path %q: expected . or [ at %q", path, rest)
+		}
+	}
+	return p, nil
+}
+
+// lookup returns the value at the path in a document decoded with encoding/json
+func (p *jsonPath) lookup(doc interface{}) (interface{}, error) {
+	value := doc
+	for n, step := range p.steps {
+		if step.key != "" {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: not an object", p.prefix(n))
+			}
+			if value, ok = object[step.key]; !ok {
+				return nil, fmt.Errorf("%s: no such key", p.prefix(n+1))
+			}
+			continue
+		}
+
+		array, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: not an array", p.prefix(n))
+		}
+		if step.index >= len(array) {
+			return nil, fmt.Errorf("%s: index out of range", p.prefix(n+1))
+		}
+		value = array[step.index]
+	}
+	return value, nil
+}
+
+// lookupNumber returns the number at the path. Strings holding a number and
+// a unit, such as "12.5 ms", are accepted too; the unit is returned if present.
+func (p *jsonPath) lookupNumber(doc interface{}) (float64, string, error) {
+	value, err := p.lookup(doc)
+	if err != nil {
+		return 0, "", err
+	}
+
+	switch v := value.(type) {
+	case float64:
+		return v, "", nil
+	case bool:
+		if v {
+			return 1, "", nil
+		}
+		return 0, "", nil
+	case string:
+		if number, unit, ok := parseNumberWithUnit(v); ok {
+			return number, unit, nil
+		}
+	}
+	return 0, "", fmt.Errorf("%s: not a number", p.String())
+}
+
+// prefix returns the path up to step n, for error messages
+func (p *jsonPath) prefix(n int) string {
+	var b strings.Builder
+	for _, step := range p.steps[:n] {
+		if step.key != "" {
+			b.WriteString("." + step.key)
+		} else {
+			fmt.Fprintf(&b, "[%d]", step.index)
+		}
+	}
+	if b.Len() == 0 {
+		return "."
+	}
+	return b.String()
+}
+
+// String returns the path as it was configured
+func (p *jsonPath) String() string {
+	return p.source
+}
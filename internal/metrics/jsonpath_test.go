@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		path  string
+		steps []jsonPathStep
+		error string
+	}{
+		{path: ""},
+		{path: "."},
+		{path: ".queue.pending", steps: []jsonPathStep{{key: "queue"}, {key: "pending"}}},
+		{path: ".workers[0].load", steps: []jsonPathStep{{key: "workers"}, {index: 0}, {key: "load"}}},
+		{path: "[2][10]", steps: []jsonPathStep{{index: 2}, {index: 10}}},
+		{path: " .total ", steps: []jsonPathStep{{key: "total"}}},
+		{path: ".queue-depth", steps: []jsonPathStep{{key: "queue-depth"}}},
+
+		{path: "..queue", error: `invalid JSON path "..queue": empty key`},
+		{path: ".queue.", error: `invalid JSON path ".queue.": empty key`},
+		{path: ".workers[0", error: `invalid JSON path ".workers[0": missing ]`},
+		{path: ".workers[-1]", error: `invalid JSON path ".workers[-1]": bad index "-1"`},
+		{path: ".workers[first]", error: `invalid JSON path ".workers[first]": bad index "first"`},
+		{path: ".workers[]", error: `invalid JSON path ".workers[]": bad index ""`},
+		{path: "queue", error: `invalid JSON path "queue": expected . or [ at "queue"`},
+		{path: ".workers[0]load", error: `invalid JSON path ".workers[0]load": expected . or [ at "load"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			p, err := parseJSONPath(test.path)
+			if test.error != "" {
+				if err == nil || err.Error() != test.error {
+					t.Errorf("error = %v, want %s", err, test.error)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(p.steps, test.steps) {
+				t.Errorf("steps = %+v, want %+v", p.steps, test.steps)
+			}
+			if p.String() != test.path {
+				t.Errorf("String() = %q, want %q", p.String(), test.path)
+			}
+		})
+	}
+}
+
+func TestJSONPathLookupNumber(t *testing.T) {
+	var doc interface{}
+	err := json.Unmarshal([]byte(`{
+		"queue": {"pending": 12, "healthy": true, "paused": false, "latency": "12.5 ms",
+			"ratio": "0.75", "state": "draining", "owner": null},
+		"workers": [{"load": 0.5}, {"load": 1.5}],
+		"count": -3e2
+	}`), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path  string
+		value float64
+		unit  string
+		error string
+	}{
+		{path: ".queue.pending", value: 12},
+		{path: ".count", value: -300},
+		{path: ".workers[1].load", value: 1.5},
+		{path: ".queue.healthy", value: 1},
+		{path: ".queue.paused", value: 0},
+		// Strings holding a number, with or without a unit
+		{path: ".queue.latency", value: 12.5, unit: "ms"},
+		{path: ".queue.ratio", value: 0.75},
+
+		{path: ".queue.state", error: ".queue.state: not a number"},
+		{path: ".queue.owner", error: ".queue.owner: not a number"},
+		{path: ".workers", error: ".workers: not a number"},
+		{path: ".queue.missing", error: ".queue.missing: no such key"},
+		{path: ".queue.pending.total", error: ".queue.pending: not an object"},
+		{path: ".queue[0]", error: ".queue: not an array"},
+		{path: "[0]", error: ".: not an array"},
+		{path: ".workers[2].load", error: ".workers[2]: index out of range"},
+		{path: ".workers.load", error: ".workers: not an object"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			p, err := parseJSONPath(test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			value, unit, err := p.lookupNumber(doc)
+			if test.error != "" {
+				if err == nil || err.Error() != test.error {
+					t.Errorf("error = %v, want %s", err, test.error)
+				}
+				return
+			}
+			if err != nil || value != test.value || unit != test.unit {
+				t.Errorf("got %v %q, %v, want %v %q", value, unit, err, test.value, test.unit)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// kernelCounters holds the cumulative counters from /proc/stat
+type kernelCounters struct {
+	contextSwitches uint64
+	interrupts      uint64
+	forks           uint64
+}
+
+var (
+	lastKernelCounters   kernelCounters
+	lastKernelSampleTime time.Time
+	kernelMutex          sync.Mutex
+)
+
+// KernelActivity contains scheduler and process table statistics
+type KernelActivity struct {
+	ContextSwitchRate float64 // Context switches per second
+	InterruptRate     float64 // Interrupts per second
+	ForkRate          float64 // Processes created per second
+	Running           int     // Runnable threads
+	Blocked           int     // Threads blocked on I/O
+	Zombies           int     // Processes that exited but were not reaped by their parent
+	Processes         int     // Total number of processes
+}
+
+// GetKernelActivity returns kernel activity rates and process counts.
+// Rates are computed from the change since the previous call, so the first call reports 0.
+func GetKernelActivity() (KernelActivity, error) {
+	kernelMutex.Lock()
+	defer kernelMutex.Unlock()
+
+	file, err := os.Open("/proc/stat")
+	if err != nil {
+		return KernelActivity{}, err
+	}
+	defer file.Close()
+
+	var counters kernelCounters
+	activity := KernelActivity{}
+
+	scanner := bufio.NewScanner(file)
+	// The intr line lists every interrupt source and can be very long
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		switch fields[0] {
+		case "ctxt":
+			counters.contextSwitches = value
+		case "intr":
+			// The first number is the total across all interrupt sources
+			counters.interrupts = value
+		case "processes":
+			counters.forks = value
+		case "procs_running":
+			activity.Running = int(value)
+		case "procs_blocked":
+			activity.Blocked = int(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return KernelActivity{}, err
+	}
+
+	now := time.Now()
+	timeDiff := now.Sub(lastKernelSampleTime).Seconds()
+
+	if !lastKernelSampleTime.IsZero() && timeDiff > 0 {
+		activity.ContextSwitchRate = counterRate(counters.contextSwitches, lastKernelCounters.contextSwitches, timeDiff)
+		activity.InterruptRate = counterRate(counters.interrupts, lastKernelCounters.interrupts, timeDiff)
+		activity.ForkRate = counterRate(counters.forks, lastKernelCounters.forks, timeDiff)
+	}
+
+	// Update last values
+	lastKernelCounters = counters
+	lastKernelSampleTime = now
+
+	// Zombies are only visible in each process's own stat file
+	pids, err := process.Pids()
+	if err != nil {
+		return activity, err
+	}
+
+	for _, pid := range pids {
+		fields, err := readProcStat(pid)
+		if err != nil || len(fields) == 0 {
+			continue
+		}
+		activity.Processes++
+		if fields[0] == "Z" {
+			activity.Zombies++
+		}
+	}
+
+	return activity, nil
+}
+
+// counterRate returns the per-second rate of a cumulative counter, or 0 if it went backwards
+func counterRate(current, last uint64, seconds float64) float64 {
+	if current < last || seconds <= 0 {
+		return 0
+	}
+	return float64(current-last) / seconds
+}
@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// Where Debian and Ubuntu record a pending reboot and update count; tests point these at fixtures
+	rebootRequiredFile = "/var/run/reboot-required"
+	updatesStampFile   = "/var/lib/update-notifier/updates-available"
+)
+
+const (
+	// The maintenance state changes slowly, so systemctl is not run on every refresh
+	maintenanceInterval = time.Minute
+	systemctlTimeout    = 5 * time.Second
+)
+
+var (
+	lastMaintenance      MaintenanceStatus
+	lastMaintenanceCheck time.Time
+	maintenanceRunning   bool
+	maintenanceMutex     sync.Mutex
+)
+
+var (
+	// "12 updates can be applied immediately." or, on older releases, "12 packages can be updated."
+	pendingUpdatesPattern = regexp.MustCompile(`(\d+) (?:updates?|packages?) can be (?:applied|updated)`)
+	// "5 of these updates are standard security updates." or "5 updates are security updates."
+	securityUpdatesPattern = regexp.MustCompile(`(\d+) (?:of these updates|updates?) (?:is|are) (?:a )?(?:standard )?security updates?`)
+)
+
+// FailedUnit is a systemd unit in the failed state
+type FailedUnit struct {
+	Unit        string `json:"unit"`
+	Description string `json:"description"`
+}
+
+// MaintenanceStatus contains pending administrative tasks
+type MaintenanceStatus struct {
+	RebootRequired  bool
+	RebootPackages  []string // Packages whose upgrade asked for the reboot
+	FailedUnits     []FailedUnit
+	UnitsError      string // Why failed units could not be listed, if they couldn't
+	PendingUpdates  int    // -1 if update-notifier is not installed
+	SecurityUpdates int
+}
+
+// ActionNeeded reports whether something needs the user's attention. Ordinary
+// updates are not included, since most machines have some pending at any time.
+func (m MaintenanceStatus) ActionNeeded() bool {
+	return m.RebootRequired || len(m.FailedUnits) > 0 || m.SecurityUpdates > 0
+}
+
+// GetMaintenanceStatus reports whether a reboot is required, which systemd units
+// have failed and how many package updates are pending; systemctlPath is the
+// systemctl binary used to list failed units. Like GetDriveHealth, it returns the
+// latest results and refreshes them in the background once they are a minute old,
+// so a hung systemd never holds up the caller. ok is false until the first check
+// has finished.
+func GetMaintenanceStatus(systemctlPath string) (status MaintenanceStatus, ok bool) {
+	maintenanceMutex.Lock()
+	defer maintenanceMutex.Unlock()
+
+	if !maintenanceRunning && time.Since(lastMaintenanceCheck) >= maintenanceInterval {
+		maintenanceRunning = true
+		go refreshMaintenanceStatus(systemctlPath)
+	}
+
+	return lastMaintenance, !lastMaintenanceCheck.IsZero()
+}
+
+// refreshMaintenanceStatus checks for pending tasks and stores the results
+func refreshMaintenanceStatus(systemctlPath string) {
+	status := checkMaintenance(systemctlPath)
+
+	maintenanceMutex.Lock()
+	defer maintenanceMutex.Unlock()
+
+	lastMaintenance = status
+	lastMaintenanceCheck = time.Now()
+	maintenanceRunning = false
+}
+
+// checkMaintenance reads the reboot flag and update stamp and lists failed units
+func checkMaintenance(systemctlPath string) MaintenanceStatus {
+	status := MaintenanceStatus{PendingUpdates: -1}
+
+	// Written by the post-install hooks of packages such as the kernel and libc
+	if _, err := os.Stat(rebootRequiredFile); err == nil {
+		status.RebootRequired = true
+		status.RebootPackages = readLines(rebootRequiredFile + ".pkgs")
+	}
+
+	status.FailedUnits, status.UnitsError = listFailedUnits(systemctlPath)
+
+	// update-notifier refreshes this stamp daily with the output of apt-check
+	if data, err := os.ReadFile(updatesStampFile); err == nil {
+		status.PendingUpdates = 0
+		if m := pendingUpdatesPattern.FindStringSubmatch(string(data)); m != nil {
+			status.PendingUpdates, _ = strconv.Atoi(m[1])
+		}
+		if m := securityUpdatesPattern.FindStringSubmatch(string(data)); m != nil {
+			status.SecurityUpdates, _ = strconv.Atoi(m[1])
+		}
+	}
+
+	return status
+}
+
+// listFailedUnits returns the failed system units, or why they could not be listed
+func listFailedUnits(systemctlPath string) ([]FailedUnit, string) {
+	output, err := runCommand(systemctlTimeout, systemctlPath, "--failed", "--output=json", "--no-pager")
+	if err != nil {
+		return nil, commandError(err)
+	}
+
+	var units []FailedUnit
+	if err := json.Unmarshal(output, &units); err != nil {
+		return nil, "unreadable systemctl output: " + err.Error()
+	}
+
+	return units, ""
+}
+
+// readLines returns the unique non-empty lines of a file, in order
+func readLines(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var lines []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !seen[line] {
+			seen[line] = true
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
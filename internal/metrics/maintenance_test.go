@@ -0,0 +1,172 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubSystemctl writes a script that records its arguments in the returned file,
+// prints output and exits with status, in place of systemctl
+func stubSystemctl(t *testing.T, output, stderr string, status int) (path, args string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	path, args = filepath.Join(dir, "systemctl"), filepath.Join(dir, "args")
+	script := fmt.Sprintf("#!/bin/sh\necho \"$@\" > %s\ncat <<'EOF'\n%s\nEOF\ncat >&2 <<'EOF'\n%s\nEOF\nexit %d\n",
+		args, output, stderr, status)
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path, args
+}
+
+// useMaintenanceFiles points checkMaintenance at fixture files in dir for the rest of the test
+func useMaintenanceFiles(t *testing.T, dir string) {
+	t.Helper()
+
+	previousReboot, previousUpdates := rebootRequiredFile, updatesStampFile
+	rebootRequiredFile = filepath.Join(dir, "reboot-required")
+	updatesStampFile = filepath.Join(dir, "updates-available")
+	t.Cleanup(func() { rebootRequiredFile, updatesStampFile = previousReboot, previousUpdates })
+}
+
+func TestCheckMaintenance(t *testing.T) {
+	dir := t.TempDir()
+	useMaintenanceFiles(t, dir)
+	writeFixture(t, dir, "reboot-required", "*** System restart required ***\n")
+	writeFixture(t, dir, "reboot-required.pkgs", "linux-image-6.8.0-45-generic\nlibc6\n\nlinux-image-6.8.0-45-generic\n")
+	writeFixture(t, dir, "updates-available", `
+12 updates can be applied immediately.
+5 of these updates are standard security updates.
+To see these additional updates run: apt list --upgradable
+`)
+
+	systemctl, args := stubSystemctl(t, `[
+		{"unit": "backup.service", "load": "loaded", "active": "failed", "sub": "failed", "description": "Nightly backup"},
+		{"unit": "certbot.timer", "load": "loaded", "active": "failed", "sub": "failed", "description": "Run certbot twice daily"}
+	]`, "", 0)
+
+	status := checkMaintenance(systemctl)
+	want := MaintenanceStatus{
+		RebootRequired: true,
+		RebootPackages: []string{"linux-image-6.8.0-45-generic", "libc6"},
+		FailedUnits: []FailedUnit{
+			{Unit: "backup.service", Description: "Nightly backup"},
+			{Unit: "certbot.timer", Description: "Run certbot twice daily"},
+		},
+		PendingUpdates:  12,
+		SecurityUpdates: 5,
+	}
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("got %+v, want %+v", status, want)
+	}
+	if !status.ActionNeeded() {
+		t.Error("no action needed")
+	}
+
+	data, err := os.ReadFile(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "--failed --output=json --no-pager" {
+		t.Errorf("systemctl was run with %q", got)
+	}
+}
+
+func TestCheckMaintenanceNothingPending(t *testing.T) {
+	// No reboot flag, and update-notifier isn't installed
+	useMaintenanceFiles(t, t.TempDir())
+	systemctl, _ := stubSystemctl(t, "[]", "", 0)
+
+	status := checkMaintenance(systemctl)
+	want := MaintenanceStatus{FailedUnits: []FailedUnit{}, PendingUpdates: -1}
+	if !reflect.DeepEqual(status, want) || status.ActionNeeded() {
+		t.Errorf("got %+v, want %+v", status, want)
+	}
+}
+
+func TestCheckMaintenanceUpdates(t *testing.T) {
+	tests := []struct {
+		stamp    string
+		pending  int
+		security int
+	}{
+		{"12 updates can be applied immediately.\n5 of these updates are standard security updates.\n", 12, 5},
+		{"1 update can be applied immediately.\n1 of these updates is a standard security update.\n", 1, 1},
+		// Releases before 20.04
+		{"34 packages can be updated.\n7 updates are security updates.\n", 34, 7},
+		{"0 updates can be applied immediately.\n", 0, 0},
+		{"", 0, 0},
+	}
+
+	systemctl, _ := stubSystemctl(t, "[]", "", 0)
+	for _, test := range tests {
+		dir := t.TempDir()
+		useMaintenanceFiles(t, dir)
+		writeFixture(t, dir, "updates-available", test.stamp)
+
+		status := checkMaintenance(systemctl)
+		if status.PendingUpdates != test.pending || status.SecurityUpdates != test.security {
+			t.Errorf("%q: %d updates, %d security, want %d and %d",
+				test.stamp, status.PendingUpdates, status.SecurityUpdates, test.pending, test.security)
+		}
+		if status.ActionNeeded() != (test.security > 0) {
+			t.Errorf("%q: ActionNeeded() = %v", test.stamp, status.ActionNeeded())
+		}
+	}
+}
+
+func TestListFailedUnitsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		stderr string
+		status int
+		want   string
+	}{
+		{"no systemd", "", "System has not been booted with systemd as init system (PID 1). Can't operate.", 1,
+			"System has not been booted with systemd as init system (PID 1). Can't operate."},
+		{"unreadable output", "UNIT LOAD ACTIVE SUB DESCRIPTION", "", 0, "unreadable systemctl output: "},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			systemctl, _ := stubSystemctl(t, test.output, test.stderr, test.status)
+			units, reason := listFailedUnits(systemctl)
+			if units != nil || !strings.HasPrefix(reason, test.want) {
+				t.Errorf("got %+v, %q, want error %q", units, reason, test.want)
+			}
+		})
+	}
+}
+
+func TestGetMaintenanceStatus(t *testing.T) {
+	useMaintenanceFiles(t, t.TempDir())
+	systemctl, _ := stubSystemctl(t, `[{"unit": "backup.service", "description": "Nightly backup"}]`, "", 0)
+
+	maintenanceMutex.Lock()
+	lastMaintenance, lastMaintenanceCheck, maintenanceRunning = MaintenanceStatus{}, time.Time{}, false
+	maintenanceMutex.Unlock()
+
+	// systemctl runs in the background, so the first call has no results
+	if status, ok := GetMaintenanceStatus(systemctl); ok {
+		t.Fatalf("first call returned %+v", status)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		if status, ok := GetMaintenanceStatus(systemctl); ok {
+			if len(status.FailedUnits) != 1 || status.FailedUnits[0].Unit != "backup.service" {
+				t.Errorf("got %+v", status)
+			}
+			return
+		}
+	}
+	t.Fatal("the check never finished")
+}
@@ -8,6 +8,14 @@ import (
 
 // GetMemoryUsage returns the current memory usage as a percentage
 func GetMemoryUsage() (float64, error) {
+	if ContainerMode() {
+		used, limit, err := getCgroupMemory()
+		if err != nil {
+			return 0, err
+		}
+		return float64(used) / float64(limit) * 100, nil
+	}
+
 	memInfo, err := mem.VirtualMemory()
 	if err != nil {
 		return 0, err
@@ -18,6 +26,15 @@ func GetMemoryUsage() (float64, error) {
 
 // GetMemoryUsageDetails returns detailed memory usage information
 func GetMemoryUsageDetails() (string, error) {
+	if ContainerMode() {
+		used, limit, err := getCgroupMemory()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d MB / %d MB (%.1f%%)", used/1024/1024, limit/1024/1024,
+			float64(used)/float64(limit)*100), nil
+	}
+
 	memInfo, err := mem.VirtualMemory()
 	if err != nil {
 		return "", err
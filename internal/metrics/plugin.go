@@ -0,0 +1,481 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// PluginProtocolVersion is the version of the JSON-lines protocol spoken with plugins
+	PluginProtocolVersion = 1
+
+	// pluginHandshakeTimeout is how long a plugin has to declare its metrics after starting
+	pluginHandshakeTimeout = 10 * time.Second
+
+	// pluginMaxLine is the longest message a plugin may send
+	pluginMaxLine = 1024 * 1024
+)
+
+var (
+	// pluginStopTimeout is how long a plugin has to exit after each request to stop
+	pluginStopTimeout = 3 * time.Second
+
+	// Plugins that crash are restarted after a delay that doubles on each crash,
+	// unless the plugin had been running for pluginStableAfter
+	pluginMinBackoff  = time.Second
+	pluginMaxBackoff  = 5 * time.Minute
+	pluginStableAfter = time.Minute
+)
+
+// errPluginStopped is returned by runOnce when the plugin was stopped on request
+var errPluginStopped = errors.New("plugin stopped")
+
+// PluginMetricInfo describes a metric a plugin declared in its handshake
+type PluginMetricInfo struct {
+	Name  string   `json:"name"`
+	Label string   `json:"label,omitempty"`
+	Unit  string   `json:"unit,omitempty"`
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
+}
+
+// PluginMetricValue is the latest value of a plugin's metric
+type PluginMetricValue struct {
+	CustomMetricValue
+	Name string
+	Min  *float64
+	Max  *float64
+}
+
+// PluginStatus describes the state of a plugin process and the metrics it reports
+type PluginStatus struct {
+	Label       string
+	Running     bool      // The plugin completed its handshake and is running
+	Started     time.Time // When the running process was started
+	Restarts    int
+	LastCrash   string // Why the plugin last stopped unexpectedly
+	CrashedAt   time.Time
+	NextRestart time.Time // When a crashed plugin is started again; zero while it runs
+	Metrics     []PluginMetricValue
+	Error       string // Why the plugin couldn't be set up, if it couldn't
+}
+
+// pluginMessage is a line of the plugin protocol, in either direction
+type pluginMessage struct {
+	Type     string             `json:"type"`
+	Protocol int                `json:"protocol,omitempty"`
+	Metrics  []PluginMetricInfo `json:"metrics,omitempty"`
+	Values   map[string]float64 `json:"values,omitempty"`
+	Errors   map[string]string  `json:"errors,omitempty"`
+	Message  string             `json:"message,omitempty"`
+}
+
+// Plugin supervises a long-running executable that reports metrics as JSON lines
+// on its standard output. The plugin starts by declaring its metrics in a "hello"
+// message, is answered with "welcome", and then sends "update" messages whenever
+// its values change. A plugin that exits or breaks the protocol is restarted with
+// a growing delay.
+type Plugin struct {
+	Label   string
+	command string
+	args    []string
+
+	mutex      sync.Mutex
+	status     PluginStatus
+	lastStderr string          // Last line the running process wrote to standard error
+	undeclared map[string]bool // Undeclared metrics the running process sent values for
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewPlugin creates a supervisor for a plugin executable
+func NewPlugin(label, command string, args []string) (*Plugin, error) {
+	if command == "" {
+		return nil, fmt.Errorf("plugin %q needs a command", label)
+	}
+	return &Plugin{
+		Label:   label,
+		command: command,
+		args:    args,
+		status:  PluginStatus{Label: label},
+	}, nil
+}
+
+// Start launches the plugin and keeps it running in the background
+func (p *Plugin) Start() {
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+
+	go p.supervise()
+}
+
+// Stop asks the plugin to exit, escalating to signals if it doesn't, and waits for it
+func (p *Plugin) Stop() {
+	if p.stop == nil {
+		return
+	}
+	close(p.stop)
+	<-p.done
+}
+
+// Status returns the state of the plugin and its latest values
+func (p *Plugin) Status() PluginStatus {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	status := p.status
+	status.Metrics = make([]PluginMetricValue, len(p.status.Metrics))
+	copy(status.Metrics, p.status.Metrics)
+	return status
+}
+
+// supervise runs the plugin until stopped, restarting it when it crashes
+func (p *Plugin) supervise() {
+	defer close(p.done)
+
+	backoff := pluginMinBackoff
+	for {
+		started := time.Now()
+		err := p.runOnce()
+		if err == errPluginStopped {
+			return
+		}
+
+		// A plugin that ran for a while before crashing gets a fresh start
+		if time.Since(started) >= pluginStableAfter {
+			backoff = pluginMinBackoff
+		}
+
+		log.Printf("Plugin %s crashed: %v; restarting in %s", p.Label, err, backoff)
+		p.mutex.Lock()
+		p.status.Running = false
+		p.status.LastCrash = err.Error()
+		p.status.CrashedAt = time.Now()
+		p.status.NextRestart = time.Now().Add(backoff)
+		for n := range p.status.Metrics {
+			p.status.Metrics[n].Error = "plugin is not running"
+		}
+		p.mutex.Unlock()
+
+		select {
+		case <-time.After(backoff):
+		case <-p.stop:
+			return
+		}
+
+		backoff *= 2
+		if backoff > pluginMaxBackoff {
+			backoff = pluginMaxBackoff
+		}
+
+		p.mutex.Lock()
+		p.status.Restarts++
+		p.status.NextRestart = time.Time{}
+		p.mutex.Unlock()
+	}
+}
+
+// runOnce starts the plugin and handles its messages until it exits, breaks the
+// protocol or is stopped. It returns why the plugin is no longer running.
+func (p *Plugin) runOnce() error {
+	cmd := exec.Command(p.command, p.args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	p.status.Started = time.Now()
+	p.lastStderr = ""
+	p.mutex.Unlock()
+
+	// Standard error is passed on to the log, and its last line kept for the crash report
+	stderrDone := make(chan struct{})
+	go func() {
+		defer close(stderrDone)
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			log.Printf("Plugin %s: %s", p.Label, line)
+			p.mutex.Lock()
+			p.lastStderr = line
+			p.mutex.Unlock()
+		}
+	}()
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), pluginMaxLine)
+		for scanner.Scan() {
+			lines <- append([]byte(nil), scanner.Bytes()...)
+		}
+		readErr <- scanner.Err()
+	}()
+
+	reason := p.handle(stdin, lines)
+
+	graceful := reason == errPluginStopped
+	waitErr := p.terminate(cmd, stdin, lines, stderrDone, graceful)
+
+	if reason == errPluginStopped {
+		return reason
+	}
+	if reason == nil {
+		// Standard output was closed, which normally means the plugin exited
+		reason = errors.New("exited")
+		if err := <-readErr; err != nil {
+			reason = fmt.Errorf("reading output: %v", err)
+		} else if waitErr != nil {
+			reason = waitErr
+		}
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.lastStderr != "" {
+		return fmt.Errorf("%v: %s", reason, p.lastStderr)
+	}
+	return reason
+}
+
+// handle performs the handshake and then applies updates until the plugin closes
+// its output (nil), is stopped (errPluginStopped) or breaks the protocol
+func (p *Plugin) handle(stdin io.Writer, lines <-chan []byte) error {
+	timer := time.NewTimer(pluginHandshakeTimeout)
+	defer timer.Stop()
+
+	var hello pluginMessage
+	select {
+	case line, ok := <-lines:
+		if !ok {
+			return nil
+		}
+		if err := json.Unmarshal(line, &hello); err != nil {
+			return fmt.Errorf("invalid handshake: %v", err)
+		}
+		if err := validateHello(hello); err != nil {
+			return fmt.Errorf("invalid handshake: %v", err)
+		}
+	case <-timer.C:
+		return fmt.Errorf("no handshake within %s", pluginHandshakeTimeout)
+	case <-p.stop:
+		return errPluginStopped
+	}
+
+	if err := writePluginMessage(stdin, pluginMessage{Type: "welcome", Protocol: PluginProtocolVersion}); err != nil {
+		return fmt.Errorf("sending welcome: %v", err)
+	}
+	p.declare(hello.Metrics)
+	log.Printf("Plugin %s started with %d metrics", p.Label, len(hello.Metrics))
+
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return nil
+			}
+			var message pluginMessage
+			if err := json.Unmarshal(line, &message); err != nil {
+				log.Printf("Plugin %s sent an invalid message: %v", p.Label, err)
+				continue
+			}
+			switch message.Type {
+			case "update":
+				p.update(message)
+			case "log":
+				log.Printf("Plugin %s: %s", p.Label, message.Message)
+			default:
+				log.Printf("Plugin %s sent an unknown message type %q", p.Label, message.Type)
+			}
+		case <-p.stop:
+			return errPluginStopped
+		}
+	}
+}
+
+// validateHello checks the metrics declared in a handshake
+func validateHello(hello pluginMessage) error {
+	if hello.Type != "hello" {
+		return fmt.Errorf("expected a hello message, got %q", hello.Type)
+	}
+	if hello.Protocol != PluginProtocolVersion {
+		return fmt.Errorf("unsupported protocol version %d", hello.Protocol)
+	}
+	if len(hello.Metrics) == 0 {
+		return errors.New("no metrics declared")
+	}
+
+	seen := make(map[string]bool)
+	for _, m := range hello.Metrics {
+		if m.Name == "" {
+			return errors.New("metric without a name")
+		}
+		if seen[m.Name] {
+			return fmt.Errorf("metric %q declared twice", m.Name)
+		}
+		if m.Min != nil && m.Max != nil && *m.Min >= *m.Max {
+			return fmt.Errorf("metric %q has an empty range", m.Name)
+		}
+		seen[m.Name] = true
+	}
+	return nil
+}
+
+// declare replaces the plugin's metrics with those from a handshake, keeping the
+// values of metrics that were declared before the plugin restarted
+func (p *Plugin) declare(infos []PluginMetricInfo) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	previous := make(map[string]PluginMetricValue)
+	for _, m := range p.status.Metrics {
+		previous[m.Name] = m
+	}
+
+	metrics := make([]PluginMetricValue, 0, len(infos))
+	for _, info := range infos {
+		label := info.Label
+		if label == "" {
+			label = info.Name
+		}
+		value := PluginMetricValue{Name: info.Name, Min: info.Min, Max: info.Max}
+		if old, ok := previous[info.Name]; ok {
+			value.CustomMetricValue = old.CustomMetricValue
+		}
+		value.Label, value.Unit = label, info.Unit
+		value.Error = ""
+		metrics = append(metrics, value)
+	}
+
+	p.status.Metrics = metrics
+	p.status.Running = true
+	p.undeclared = make(map[string]bool)
+}
+
+// update applies the values and errors in an update message
+func (p *Plugin) update(message pluginMessage) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	declared := make(map[string]bool)
+	for n := range p.status.Metrics {
+		m := &p.status.Metrics[n]
+		declared[m.Name] = true
+		if value, ok := message.Values[m.Name]; ok {
+			m.Value = value
+			m.Updated = time.Now()
+			m.Error = ""
+		}
+		if reason, ok := message.Errors[m.Name]; ok {
+			m.Error = reason
+		}
+	}
+
+	// Warn once per metric, as a plugin may send the same undeclared value every second
+	for name := range message.Values {
+		if !declared[name] && !p.undeclared[name] {
+			p.undeclared[name] = true
+			log.Printf("Plugin %s sent a value for undeclared metric %q", p.Label, name)
+		}
+	}
+}
+
+// terminate makes sure the plugin and the processes it started have exited,
+// asking it to shut down first if graceful is set, and returns the result of
+// cmd.Wait. A plugin can close its output and keep running, so it only counts
+// as stopped once it has been waited for.
+func (p *Plugin) terminate(cmd *exec.Cmd, stdin io.WriteCloser, lines <-chan []byte, stderrDone <-chan struct{}, graceful bool) error {
+	if !graceful {
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		stdin.Close()
+	}
+
+	// cmd.Wait closes the pipes, so it is only called once both have been read to the end
+	var waitErr error
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		for range lines {
+		}
+		<-stderrDone
+		waitErr = cmd.Wait()
+	}()
+
+	if graceful {
+		writePluginMessage(stdin, pluginMessage{Type: "shutdown"})
+		stdin.Close()
+		if waitPluginExit(exited, pluginStopTimeout) {
+			return waitErr
+		}
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+		if waitPluginExit(exited, pluginStopTimeout) {
+			return waitErr
+		}
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+
+	// Killed processes exit at once, but one that left the process group can
+	// still hold standard error open, so don't wait for it forever
+	if waitPluginExit(exited, pluginStopTimeout) {
+		return waitErr
+	}
+	log.Printf("Plugin %s left a process holding its output open", p.Label)
+	return errors.New("killed")
+}
+
+// waitPluginExit waits up to timeout for exited to be closed, reporting whether it was
+func waitPluginExit(exited <-chan struct{}, timeout time.Duration) bool {
+	select {
+	case <-exited:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// writePluginMessage sends one message to a plugin's standard input
+func writePluginMessage(w io.Writer, message pluginMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// GetPluginStatuses returns the state of each plugin, in the order given
+func GetPluginStatuses(plugins []*Plugin) []PluginStatus {
+	statuses := make([]PluginStatus, 0, len(plugins))
+	for _, p := range plugins {
+		statuses = append(statuses, p.Status())
+	}
+	return statuses
+}
@@ -0,0 +1,213 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// helloLine declares a single metric, as the first line a plugin sends
+const helloLine = `{"type": "hello", "protocol": 1, "metrics": [{"name": "failing", "label": "Failing tests", "unit": "tests"}]}`
+
+// stubPlugin writes a shell script to run as a plugin
+func stubPlugin(t *testing.T, script string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "plugin")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// startPlugin starts a plugin running script
+func startPlugin(t *testing.T, script string) *Plugin {
+	t.Helper()
+
+	plugin, err := NewPlugin("CI", stubPlugin(t, script), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plugin.Start()
+	return plugin
+}
+
+// waitForStatus polls the plugin until done accepts its status
+func waitForStatus(t *testing.T, plugin *Plugin, done func(PluginStatus) bool) PluginStatus {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status := plugin.Status()
+		if done(status) {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("gave up waiting, status is %+v", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// shortenPluginTimers makes plugins restart and stop quickly for the rest of the test
+func shortenPluginTimers(t *testing.T) {
+	previous := []time.Duration{pluginStopTimeout, pluginMinBackoff, pluginMaxBackoff}
+	pluginStopTimeout, pluginMinBackoff, pluginMaxBackoff = 200*time.Millisecond, 50*time.Millisecond, 200*time.Millisecond
+	t.Cleanup(func() {
+		pluginStopTimeout, pluginMinBackoff, pluginMaxBackoff = previous[0], previous[1], previous[2]
+	})
+}
+
+func TestPluginHandshake(t *testing.T) {
+	welcome := filepath.Join(t.TempDir(), "welcome")
+	plugin := startPlugin(t, `
+echo '`+helloLine+`'
+read line
+echo "$line" > `+welcome+`
+echo '{"type": "update", "values": {"failing": 3, "undeclared": 1}}'
+read line
+`)
+	defer plugin.Stop()
+
+	status := waitForStatus(t, plugin, func(s PluginStatus) bool {
+		return len(s.Metrics) == 1 && !s.Metrics[0].Updated.IsZero()
+	})
+	if !status.Running || status.Started.IsZero() || status.Restarts != 0 {
+		t.Errorf("status = %+v, want running", status)
+	}
+	metric := status.Metrics[0]
+	if metric.Name != "failing" || metric.Label != "Failing tests" || metric.Unit != "tests" || metric.Value != 3 {
+		t.Errorf("metric = %+v", metric)
+	}
+
+	data, err := os.ReadFile(welcome)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.TrimSpace(string(data)), `{"type":"welcome","protocol":1}`; got != want {
+		t.Errorf("plugin was sent %s, want %s", got, want)
+	}
+}
+
+func TestPluginBadHello(t *testing.T) {
+	tests := []struct {
+		name  string
+		hello string
+		want  string
+	}{
+		{"not JSON", "ready", "invalid handshake: invalid character"},
+		{"wrong type", `{"type": "update", "protocol": 1}`, `invalid handshake: expected a hello message, got "update"`},
+		{"newer protocol", `{"type": "hello", "protocol": 2, "metrics": [{"name": "a"}]}`, "invalid handshake: unsupported protocol version 2"},
+		{"no metrics", `{"type": "hello", "protocol": 1}`, "invalid handshake: no metrics declared"},
+		{"repeated metric", `{"type": "hello", "protocol": 1, "metrics": [{"name": "a"}, {"name": "a"}]}`, `invalid handshake: metric "a" declared twice`},
+		{"empty range", `{"type": "hello", "protocol": 1, "metrics": [{"name": "a", "min": 1, "max": 1}]}`, `invalid handshake: metric "a" has an empty range`},
+	}
+
+	shortenPluginTimers(t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// The plugin would wait for its welcome forever, so it has to be killed
+			plugin := startPlugin(t, "echo '"+test.hello+"'\nsleep 60\n")
+			defer plugin.Stop()
+
+			status := waitForStatus(t, plugin, func(s PluginStatus) bool { return !s.NextRestart.IsZero() })
+			if status.Running || len(status.Metrics) != 0 {
+				t.Errorf("status = %+v, want not running", status)
+			}
+			if !strings.HasPrefix(status.LastCrash, test.want) {
+				t.Errorf("crash = %q, want %q", status.LastCrash, test.want)
+			}
+		})
+	}
+}
+
+func TestPluginRestartBackoff(t *testing.T) {
+	shortenPluginTimers(t)
+	plugin := startPlugin(t, "echo 'config not found' >&2\nexit 3\n")
+	defer plugin.Stop()
+
+	// Record the delay before each restart until it stops growing
+	var delays []time.Duration
+	var crashed time.Time
+	waitForStatus(t, plugin, func(s PluginStatus) bool {
+		if !s.NextRestart.IsZero() && !s.CrashedAt.Equal(crashed) {
+			crashed = s.CrashedAt
+			delays = append(delays, s.NextRestart.Sub(s.CrashedAt).Round(10*time.Millisecond))
+			if s.LastCrash != "exit status 3: config not found" {
+				t.Errorf("crash = %q", s.LastCrash)
+			}
+		}
+		return len(delays) == 5
+	})
+
+	want := []time.Duration{50, 100, 200, 200, 200}
+	for n := range want {
+		if delays[n] != want[n]*time.Millisecond {
+			t.Errorf("restart delays = %v, want %v milliseconds", delays, want)
+			break
+		}
+	}
+	if status := plugin.Status(); status.Restarts < 4 {
+		t.Errorf("restarted %d times", status.Restarts)
+	}
+}
+
+func TestPluginStop(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{"exits on shutdown", "read line\nread line\n"},
+		// Needs SIGTERM
+		{"ignores shutdown", "read line\nread line\nsleep 60\n"},
+		// Needs SIGKILL, which also reaches the sleep it started
+		{"ignores SIGTERM", "trap '' TERM\nread line\nread line\nsleep 60\n"},
+		// Closing its output doesn't mean the plugin has exited
+		{"closes its output", "trap '' TERM\nread line\nread line\nexec >&- 2>&-\nsleep 60\n"},
+	}
+
+	shortenPluginTimers(t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plugin := startPlugin(t, "echo '"+helloLine+"'\n"+test.script)
+			waitForStatus(t, plugin, func(s PluginStatus) bool { return s.Running })
+
+			start := time.Now()
+			plugin.Stop()
+			// Up to pluginStopTimeout for each of shutdown, SIGTERM and SIGKILL
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("Stop took %s", elapsed)
+			}
+			if status := plugin.Status(); status.LastCrash != "" {
+				t.Errorf("stopping counted as a crash: %q", status.LastCrash)
+			}
+		})
+	}
+}
+
+func TestPluginClosesOutput(t *testing.T) {
+	shortenPluginTimers(t)
+	// The plugin stops talking but keeps running, which counts as a crash
+	plugin := startPlugin(t, "echo '"+helloLine+"'\nread line\nexec >&-\nsleep 60\n")
+	defer plugin.Stop()
+
+	status := waitForStatus(t, plugin, func(s PluginStatus) bool { return !s.NextRestart.IsZero() })
+	if status.Running || !strings.HasPrefix(status.LastCrash, "signal: killed") {
+		t.Errorf("status = %+v, want a crash", status)
+	}
+}
+
+func TestPluginStopWhileRestarting(t *testing.T) {
+	shortenPluginTimers(t)
+	pluginMinBackoff = time.Hour
+	plugin := startPlugin(t, "exit 1\n")
+	waitForStatus(t, plugin, func(s PluginStatus) bool { return !s.NextRestart.IsZero() })
+
+	start := time.Now()
+	plugin.Stop()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Stop waited %s for the restart", elapsed)
+	}
+}
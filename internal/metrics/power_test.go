@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// usePowercapRoot points GetPowerUsage at a fixture tree for the rest of the test
+func usePowercapRoot(t *testing.T, root string) {
+	t.Helper()
+
+	powerMutex.Lock()
+	previous := powercapRoot
+	powercapRoot = root
+	lastEnergy, lastPowerSample = make(map[string]uint64), time.Time{}
+	powerMutex.Unlock()
+
+	t.Cleanup(func() {
+		powerMutex.Lock()
+		powercapRoot = previous
+		lastEnergy, lastPowerSample = make(map[string]uint64), time.Time{}
+		powerMutex.Unlock()
+	})
+}
+
+// writeZone writes a RAPL zone in a fixture powercap tree. A zero maxRange leaves out max_energy_range_uj.
+func writeZone(t *testing.T, root, zone, name string, energy, maxRange uint64) {
+	t.Helper()
+
+	writeFixture(t, root, filepath.Join(zone, "name"), name+"\n")
+	writeFixture(t, root, filepath.Join(zone, "energy_uj"), itoa(energy)+"\n")
+	if maxRange > 0 {
+		writeFixture(t, root, filepath.Join(zone, "max_energy_range_uj"), itoa(maxRange)+"\n")
+	}
+}
+
+// samplePower reads the fixture counters as if the previous sample was taken a second ago
+func samplePower(t *testing.T) map[string]float64 {
+	t.Helper()
+
+	powerMutex.Lock()
+	lastPowerSample = lastPowerSample.Add(-time.Second)
+	powerMutex.Unlock()
+
+	usage, err := GetPowerUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	watts := make(map[string]float64)
+	for _, zone := range usage.Zones {
+		watts[zone.Name] = zone.Watts
+	}
+	watts["total"] = usage.PackageWatts
+	return watts
+}
+
+func TestGetPowerUsage(t *testing.T) {
+	root := t.TempDir()
+	usePowercapRoot(t, root)
+
+	const maxRange = 262143328850
+	writeZone(t, root, "intel-rapl:0", "package-0", 1000000, maxRange)
+	writeZone(t, root, "intel-rapl:0:0", "core", maxRange-2000000, maxRange)
+	writeZone(t, root, "intel-rapl:1", "package-1", 50000000, 0)
+
+	watts := samplePower(t)
+	if len(watts) != 4 || watts["total"] != 0 {
+		t.Fatalf("first sample = %v, want every zone at 0 W", watts)
+	}
+
+	// A second later, package-0 used 15 J, and the core counter wrapped after using 5 J.
+	// package-1 wrapped too, but without max_energy_range_uj its usage is unknown.
+	writeZone(t, root, "intel-rapl:0", "package-0", 16000000, maxRange)
+	writeZone(t, root, "intel-rapl:0:0", "core", 3000000, maxRange)
+	writeZone(t, root, "intel-rapl:1", "package-1", 1000000, 0)
+
+	watts = samplePower(t)
+	checkNear(t, "package-0", watts["package-0"], 15)
+	checkNear(t, "package-0 core", watts["package-0 core"], 5)
+	if w, ok := watts["package-1"]; !ok || w != 0 {
+		t.Errorf("package-1 = %v W, listed %v, want 0 W while its wrap can't be measured", w, ok)
+	}
+	checkNear(t, "total", watts["total"], 15)
+
+	// The wrapped counter is measured from its new value on the next sample
+	writeZone(t, root, "intel-rapl:1", "package-1", 9000000, 0)
+	watts = samplePower(t)
+	checkNear(t, "package-1 after the wrap", watts["package-1"], 8)
+	if watts["package-0"] != 0 || watts["package-0 core"] != 0 {
+		t.Errorf("idle zones draw power: %v", watts)
+	}
+}
+
+func TestGetPowerUsageWithoutRAPL(t *testing.T) {
+	usePowercapRoot(t, t.TempDir())
+
+	if _, err := GetPowerUsage(); err != ErrNoRAPL {
+		t.Errorf("error = %v, want ErrNoRAPL", err)
+	}
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+var (
+	lastProcCPUTimes   = make(map[int32]float64)
+	lastProcSampleTime time.Time
+	processMutex       sync.Mutex
+)
+
+// ProcessInfo contains usage statistics for a single process
+type ProcessInfo struct {
+	PID        int32
+	Name       string
+	CPUPercent float64 // CPU usage since the previous sample, 100% = one core
+	MemoryRSS  uint64  // Resident memory in bytes
+	Nice       int32
+}
+
+// GetTopProcesses returns up to limit processes ordered by CPU usage.
+// CPU usage is computed from the change in CPU time since the previous call,
+// so the first call reports 0% for every process.
+func GetTopProcesses(limit int) ([]ProcessInfo, error) {
+	processMutex.Lock()
+	defer processMutex.Unlock()
+
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	timeDiff := now.Sub(lastProcSampleTime).Seconds()
+	cpuTimes := make(map[int32]float64, len(procs))
+
+	var result []ProcessInfo
+	for _, p := range procs {
+		// Processes can exit while we iterate, so skip any we can no longer read
+		times, err := p.Times()
+		if err != nil {
+			continue
+		}
+		name, err := p.Name()
+		if err != nil {
+			continue
+		}
+
+		total := times.User + times.System
+		cpuTimes[p.Pid] = total
+
+		info := ProcessInfo{
+			PID:  p.Pid,
+			Name: name,
+		}
+
+		if last, ok := lastProcCPUTimes[p.Pid]; ok && timeDiff > 0 && total >= last {
+			info.CPUPercent = (total - last) / timeDiff * 100
+		}
+
+		if memInfo, err := p.MemoryInfo(); err == nil {
+			info.MemoryRSS = memInfo.RSS
+		}
+
+		if nice, err := readNice(p.Pid); err == nil {
+			info.Nice = nice
+		}
+
+		result = append(result, info)
+	}
+
+	// Update last values
+	lastProcCPUTimes = cpuTimes
+	lastProcSampleTime = now
+
+	sort.Slice(result, func(a, b int) bool {
+		if result[a].CPUPercent == result[b].CPUPercent {
+			return result[a].MemoryRSS > result[b].MemoryRSS
+		}
+		return result[a].CPUPercent > result[b].CPUPercent
+	})
+
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	// ReniceStep is how much LowerProcessPriority raises a process's nice value
+	ReniceStep = 5
+
+	// maxNice is the lowest scheduling priority a process can have
+	maxNice = 19
+)
+
+// ErrPermissionDenied is returned when the current user is not allowed to act on a process
+var ErrPermissionDenied = errors.New("permission denied")
+
+// TerminateProcess asks a process to exit by sending it SIGTERM
+func TerminateProcess(pid int32) error {
+	return signalProcess(pid, syscall.SIGTERM)
+}
+
+// KillProcess forcibly stops a process by sending it SIGKILL
+func KillProcess(pid int32) error {
+	return signalProcess(pid, syscall.SIGKILL)
+}
+
+// LowerProcessPriority raises the nice value of a process by ReniceStep
+// and returns the new nice value
+func LowerProcessPriority(pid int32) (int32, error) {
+	if pid <= 1 {
+		return 0, fmt.Errorf("refusing to renice process %d", pid)
+	}
+
+	nice, err := readNice(pid)
+	if err != nil {
+		return 0, fmt.Errorf("process %d no longer exists", pid)
+	}
+
+	newNice := nice + ReniceStep
+	if newNice > maxNice {
+		newNice = maxNice
+	}
+
+	if err := syscall.Setpriority(syscall.PRIO_PROCESS, int(pid), int(newNice)); err != nil {
+		return nice, processError(pid, "change the priority of", err)
+	}
+
+	return newNice, nil
+}
+
+// readNice returns the nice value of a process from /proc/[pid]/stat
+func readNice(pid int32) (int32, error) {
+	fields, err := readProcStat(pid)
+	if err != nil {
+		return 0, err
+	}
+
+	// Fields after the name start at "state"; nice is the 19th field overall
+	if len(fields) < 17 {
+		return 0, fmt.Errorf("unexpected stat format for process %d", pid)
+	}
+
+	nice, err := strconv.ParseInt(fields[16], 10, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	return int32(nice), nil
+}
+
+// readProcStat returns the fields of /proc/[pid]/stat that follow the command name,
+// so the first field is the process state
+func readProcStat(pid int32) ([]string, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return nil, err
+	}
+
+	// The command name may contain spaces, so start after its closing parenthesis
+	stat := string(data)
+	end := strings.LastIndex(stat, ")")
+	if end < 0 {
+		return nil, fmt.Errorf("unexpected stat format for process %d", pid)
+	}
+
+	return strings.Fields(stat[end+1:]), nil
+}
+
+// signalProcess sends a signal to a process and translates common failures
+func signalProcess(pid int32, sig syscall.Signal) error {
+	if pid <= 1 {
+		return fmt.Errorf("refusing to signal process %d", pid)
+	}
+
+	if err := syscall.Kill(int(pid), sig); err != nil {
+		return processError(pid, fmt.Sprintf("send %s to", sig), err)
+	}
+
+	return nil
+}
+
+// processError turns a syscall error into a message suitable for the menu
+func processError(pid int32, action string, err error) error {
+	switch err {
+	case syscall.EPERM, syscall.EACCES:
+		return fmt.Errorf("%w: cannot %s process %d", ErrPermissionDenied, action, pid)
+	case syscall.ESRCH:
+		return fmt.Errorf("process %d no longer exists", pid)
+	default:
+		return fmt.Errorf("cannot %s process %d: %v", action, pid, err)
+	}
+}
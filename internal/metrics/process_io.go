@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// ioSample holds the cumulative I/O counters of a process at the last sample
+type ioSample struct {
+	readBytes  uint64
+	writeBytes uint64
+}
+
+var (
+	lastProcIO           = make(map[int32]ioSample)
+	lastProcIOSampleTime time.Time
+	processIOMutex       sync.Mutex
+)
+
+// ProcessIO contains the disk throughput of a single process
+type ProcessIO struct {
+	PID        int32
+	Name       string
+	ReadSpeed  float64 // Bytes per second read from storage
+	WriteSpeed float64 // Bytes per second written to storage
+}
+
+// GetTopDiskIO returns up to limit processes ordered by disk throughput.
+// Rates come from the read_bytes and write_bytes deltas in /proc/[pid]/io, which only
+// count I/O that reached the block layer. Other users' counters are only readable as root.
+func GetTopDiskIO(limit int) ([]ProcessIO, error) {
+	processIOMutex.Lock()
+	defer processIOMutex.Unlock()
+
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	timeDiff := now.Sub(lastProcIOSampleTime).Seconds()
+	samples := make(map[int32]ioSample, len(procs))
+
+	var result []ProcessIO
+	for _, p := range procs {
+		counters, err := p.IOCounters()
+		if err != nil {
+			continue
+		}
+
+		sample := ioSample{readBytes: counters.ReadBytes, writeBytes: counters.WriteBytes}
+		samples[p.Pid] = sample
+
+		last, ok := lastProcIO[p.Pid]
+		if !ok || timeDiff <= 0 || sample.readBytes < last.readBytes || sample.writeBytes < last.writeBytes {
+			continue
+		}
+
+		// Only processes that actually did I/O during the interval are ranked
+		if sample.readBytes == last.readBytes && sample.writeBytes == last.writeBytes {
+			continue
+		}
+
+		name, err := p.Name()
+		if err != nil {
+			continue
+		}
+
+		result = append(result, ProcessIO{
+			PID:        p.Pid,
+			Name:       name,
+			ReadSpeed:  float64(sample.readBytes-last.readBytes) / timeDiff,
+			WriteSpeed: float64(sample.writeBytes-last.writeBytes) / timeDiff,
+		})
+	}
+
+	// Update last values
+	lastProcIO = samples
+	lastProcIOSampleTime = now
+
+	sort.Slice(result, func(a, b int) bool {
+		return result[a].ReadSpeed+result[a].WriteSpeed > result[b].ReadSpeed+result[b].WriteSpeed
+	})
+
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
@@ -0,0 +1,197 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// ProcessWatch matches a group of processes whose combined usage is reported as one metric.
+// A watch matches by executable name, command line regex or pidfile, whichever is set.
+type ProcessWatch struct {
+	Label          string
+	name           string
+	cmdline        *regexp.Regexp
+	pidfile        string
+	lastCPUTimes   map[int32]float64
+	lastSampleTime time.Time
+	mutex          sync.Mutex
+}
+
+// WatchedProcessStats contains the combined usage of the processes matched by a watch
+type WatchedProcessStats struct {
+	Label      string
+	Running    bool
+	Count      int           // Number of matching processes
+	CPUPercent float64       // Combined CPU usage, 100% = one core
+	MemoryRSS  uint64        // Combined resident memory in bytes
+	NumThreads int32         // Combined thread count
+	NumFDs     int32         // Combined open file descriptors, -1 if they could not be read
+	Uptime     time.Duration // Age of the oldest matching process
+	Error      string        // Why the watch couldn't be set up, if it couldn't
+}
+
+// NewProcessWatch creates a watch. Exactly one of name, cmdline and pidfile should be set;
+// if several are, pidfile takes precedence over cmdline, and cmdline over name.
+func NewProcessWatch(label, name, cmdline, pidfile string) (*ProcessWatch, error) {
+	w := &ProcessWatch{
+		Label:        label,
+		name:         name,
+		pidfile:      pidfile,
+		lastCPUTimes: make(map[int32]float64),
+	}
+
+	if cmdline != "" {
+		re, err := regexp.Compile(cmdline)
+		if err != nil {
+			return nil, fmt.Errorf("invalid command line pattern for %q: %v", label, err)
+		}
+		w.cmdline = re
+	}
+
+	if name == "" && cmdline == "" && pidfile == "" {
+		return nil, fmt.Errorf("process watch %q needs a name, cmdline or pidfile", label)
+	}
+
+	return w, nil
+}
+
+// matches reports whether a process belongs to the watch
+func (w *ProcessWatch) matches(p *process.Process) bool {
+	if w.cmdline != nil {
+		cmdline, err := p.Cmdline()
+		return err == nil && w.cmdline.MatchString(cmdline)
+	}
+
+	name, err := p.Name()
+	if err == nil && name == w.name {
+		return true
+	}
+
+	// The kernel truncates process names to 15 characters, so fall back to argv[0]
+	args, err := p.CmdlineSlice()
+	return err == nil && len(args) > 0 && filepath.Base(args[0]) == w.name
+}
+
+// pidfileProcess returns the process named by the watch's pidfile
+func (w *ProcessWatch) pidfileProcess() (*process.Process, error) {
+	data, err := os.ReadFile(w.pidfile)
+	if err != nil {
+		return nil, err
+	}
+
+	pid, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pidfile %s: %v", w.pidfile, err)
+	}
+
+	return process.NewProcess(int32(pid))
+}
+
+// collect aggregates the usage of the given processes
+func (w *ProcessWatch) collect(procs []*process.Process) WatchedProcessStats {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	now := time.Now()
+	timeDiff := now.Sub(w.lastSampleTime).Seconds()
+	cpuTimes := make(map[int32]float64, len(procs))
+	stats := WatchedProcessStats{Label: w.Label}
+
+	var oldest int64
+	for _, p := range procs {
+		times, err := p.Times()
+		if err != nil {
+			// The process exited after it was matched
+			continue
+		}
+
+		stats.Running = true
+		stats.Count++
+
+		total := times.User + times.System
+		cpuTimes[p.Pid] = total
+		if last, ok := w.lastCPUTimes[p.Pid]; ok && timeDiff > 0 && total >= last {
+			stats.CPUPercent += (total - last) / timeDiff * 100
+		}
+
+		if memInfo, err := p.MemoryInfo(); err == nil {
+			stats.MemoryRSS += memInfo.RSS
+		}
+
+		if threads, err := p.NumThreads(); err == nil {
+			stats.NumThreads += threads
+		}
+
+		// Other users' file descriptors are not readable without privileges
+		if stats.NumFDs >= 0 {
+			if fds, err := p.NumFDs(); err == nil {
+				stats.NumFDs += fds
+			} else {
+				stats.NumFDs = -1
+			}
+		}
+
+		if created, err := p.CreateTime(); err == nil && (oldest == 0 || created < oldest) {
+			oldest = created
+		}
+	}
+
+	if oldest > 0 {
+		stats.Uptime = now.Sub(time.Unix(0, oldest*int64(time.Millisecond)))
+	}
+
+	// Update last values
+	w.lastCPUTimes = cpuTimes
+	w.lastSampleTime = now
+
+	return stats
+}
+
+// GetWatchedProcesses returns the combined usage for each watch.
+// The process table is only scanned once, however many watches there are.
+func GetWatchedProcesses(watches []*ProcessWatch) ([]WatchedProcessStats, error) {
+	matched := make([][]*process.Process, len(watches))
+
+	needScan := false
+	for n, w := range watches {
+		if w.pidfile == "" {
+			needScan = true
+			continue
+		}
+
+		// A missing pidfile or stale PID simply means the process is not running
+		if p, err := w.pidfileProcess(); err == nil {
+			matched[n] = append(matched[n], p)
+		}
+	}
+
+	if needScan {
+		procs, err := process.Processes()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range procs {
+			for n, w := range watches {
+				if w.pidfile == "" && w.matches(p) {
+					matched[n] = append(matched[n], p)
+				}
+			}
+		}
+	}
+
+	stats := make([]WatchedProcessStats, len(watches))
+	for n, w := range watches {
+		stats[n] = w.collect(matched[n])
+	}
+
+	return stats, nil
+}
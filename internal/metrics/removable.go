@@ -0,0 +1,374 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// mountPollTimeout is how long the watcher waits for a mount event before checking whether it was stopped
+	mountPollTimeout = time.Second
+
+	// removableUsageInterval is how often the usage of mounted removable drives is refreshed
+	removableUsageInterval = 5 * time.Second
+
+	// unmountTimeout bounds an unmount, which waits for pending writes to be flushed to the drive
+	unmountTimeout = 2 * time.Minute
+)
+
+// sysBlockDir lists the block devices in sysfs; tests point it at a fixture tree
+var sysBlockDir = "/sys/class/block"
+
+// RemovableDrive is a mounted USB drive or SD card
+type RemovableDrive struct {
+	Device      string // e.g. "/dev/sdb1"
+	MountPoint  string
+	Label       string // Filesystem label, or the mount point's name if there is none
+	FSType      string
+	Total       uint64
+	Used        uint64
+	UsedPercent float64
+	Error       string // Why the usage could not be read, if it couldn't
+}
+
+// MountWatcher keeps track of mounted removable drives. It waits for the kernel
+// to signal a change to /proc/self/mountinfo instead of rereading it on every
+// refresh. The drives' usage is read in a goroutine of its own, so that a slow or
+// disconnected drive holds up neither the other metrics nor noticing unmounts.
+type MountWatcher struct {
+	drives      []RemovableDrive
+	mountPoints []string // Block device mount points, as listed by GetMountPoints
+	mutex       sync.Mutex
+	mounted     chan struct{} // Asks for the usage of newly mounted drives to be read right away
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+// NewMountWatcher creates a watcher; Drives is empty until it has been started
+func NewMountWatcher() *MountWatcher {
+	return &MountWatcher{}
+}
+
+// Start begins watching for mounts in the background
+func (w *MountWatcher) Start() {
+	w.mounted = make(chan struct{}, 1)
+	w.stop = make(chan struct{})
+	w.done = make(chan struct{})
+
+	go w.run()
+	go w.watchUsage()
+}
+
+// Stop stops watching and waits for the watcher to finish. A usage refresh in
+// progress isn't waited for, as it can be stuck on a hung drive.
+func (w *MountWatcher) Stop() {
+	if w.stop == nil {
+		return
+	}
+	close(w.stop)
+	<-w.done
+}
+
+// Drives returns the mounted removable drives, sorted by mount point
+func (w *MountWatcher) Drives() []RemovableDrive {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	drives := make([]RemovableDrive, len(w.drives))
+	copy(drives, w.drives)
+	return drives
+}
+
+// MountPoints returns the mount points of filesystems on block devices, like
+// GetMountPoints, as of the last change to the mount table. It is nil until the
+// watcher has read the mount table.
+func (w *MountWatcher) MountPoints() []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.mountPoints == nil {
+		return nil
+	}
+	mountPoints := make([]string, len(w.mountPoints))
+	copy(mountPoints, w.mountPoints)
+	return mountPoints
+}
+
+// run rereads the mount table whenever it changes
+func (w *MountWatcher) run() {
+	defer close(w.done)
+
+	file, err := openMountinfo()
+	if err != nil {
+		log.Printf("Failed to watch mounts: %v", err)
+		return
+	}
+	defer file.Close()
+
+	changed := true
+	for {
+		if changed {
+			// Reading the file from the start also rearms the change notification
+			if _, err := file.Seek(0, io.SeekStart); err == nil {
+				if data, err := io.ReadAll(file); err == nil {
+					drives := parseRemovableMounts(string(data), filesystemLabels(), isRemovableDevice)
+					w.setMounts(drives, parseMountPoints(string(data)))
+					select {
+					case w.mounted <- struct{}{}:
+					default:
+					}
+				}
+			}
+		}
+
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+
+		changed, err = waitForMountChange(file, mountPollTimeout)
+		if err != nil {
+			// Without notifications, fall back to rereading the mount table on every wakeup
+			time.Sleep(mountPollTimeout)
+			changed = true
+		}
+	}
+}
+
+// watchUsage refreshes the drives' usage every removableUsageInterval and after
+// mounts change. It runs apart from run because statfs on a drive that was pulled
+// out or has hung can block for a long time, and unmounts must still be noticed.
+func (w *MountWatcher) watchUsage() {
+	ticker := time.NewTicker(removableUsageInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.mounted:
+		case <-w.stop:
+			return
+		}
+		w.refreshUsage()
+	}
+}
+
+// setMounts replaces the list of drives, keeping the usage of drives that are still
+// mounted, and the list of mount points
+func (w *MountWatcher) setMounts(mounts []RemovableDrive, mountPoints []string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	previous := make(map[string]RemovableDrive)
+	for _, d := range w.drives {
+		previous[d.Device+" "+d.MountPoint] = d
+	}
+
+	for n, d := range mounts {
+		if old, ok := previous[d.Device+" "+d.MountPoint]; ok {
+			mounts[n] = old
+			delete(previous, d.Device+" "+d.MountPoint)
+		} else {
+			log.Printf("Removable drive %s mounted at %s", d.Device, d.MountPoint)
+		}
+	}
+	for _, d := range previous {
+		log.Printf("Removable drive %s unmounted from %s", d.Device, d.MountPoint)
+	}
+
+	w.drives = mounts
+	w.mountPoints = append([]string{}, mountPoints...) // Not nil, even without mount points
+}
+
+// refreshUsage reads the usage of each drive without holding the lock during the statfs calls
+func (w *MountWatcher) refreshUsage() {
+	drives := w.Drives()
+	for n := range drives {
+		d := &drives[n]
+		usage, err := disk.Usage(d.MountPoint)
+		if err != nil {
+			d.Error = err.Error()
+			continue
+		}
+		d.Total, d.Used, d.UsedPercent, d.Error = usage.Total, usage.Used, usage.UsedPercent, ""
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	// Only keep the usage of drives that weren't unmounted in the meantime
+	usage := make(map[string]RemovableDrive)
+	for _, d := range drives {
+		usage[d.Device+" "+d.MountPoint] = d
+	}
+	for n, d := range w.drives {
+		if updated, ok := usage[d.Device+" "+d.MountPoint]; ok {
+			w.drives[n] = updated
+		}
+	}
+}
+
+// openMountinfo opens the mount table for waitForMountChange. os.Open would add
+// it to Go's poller, whose own polling consumes the change notifications, so it
+// is opened in blocking mode, which the poller leaves alone.
+func openMountinfo() (*os.File, error) {
+	fd, err := unix.Open("/proc/self/mountinfo", unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: "/proc/self/mountinfo", Err: err}
+	}
+	return os.NewFile(uintptr(fd), "/proc/self/mountinfo"), nil
+}
+
+// waitForMountChange waits until the kernel reports a change to the mount table.
+// mountinfo signals changes as an exceptional condition, POLLPRI and POLLERR.
+// poll is used rather than select, which can't watch descriptors above 1023.
+func waitForMountChange(file *os.File, timeout time.Duration) (bool, error) {
+	fds := []unix.PollFd{{Fd: int32(file.Fd()), Events: unix.POLLPRI | unix.POLLERR}}
+
+	n, err := unix.Poll(fds, int(timeout/time.Millisecond))
+	if err == unix.EINTR {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return n > 0 && fds[0].Revents&(unix.POLLPRI|unix.POLLERR) != 0, nil
+}
+
+// parseRemovableMounts returns the removable drives in a mountinfo table, given
+// the filesystem labels by device node and a test for removable devices
+func parseRemovableMounts(mountinfo string, labels map[string]string, removable func(device string) bool) []RemovableDrive {
+	var drives []RemovableDrive
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(mountinfo, "\n") {
+		fields := strings.Fields(line)
+
+		sep := -1
+		for n, field := range fields {
+			if field == "-" {
+				sep = n
+				break
+			}
+		}
+		if sep < 5 || len(fields) < sep+3 {
+			continue
+		}
+
+		// Only the first mount of a drive is listed, as bind mounts show the same filesystem
+		mountPoint, fsType, device := unescapeMountPath(fields[4]), fields[sep+1], fields[sep+2]
+		if !strings.HasPrefix(device, "/dev/") || seen[device] || !removable(device) {
+			continue
+		}
+		seen[device] = true
+
+		label := labels[resolveDevice(device)]
+		if label == "" {
+			label = filepath.Base(mountPoint)
+		}
+		drives = append(drives, RemovableDrive{
+			Device:     device,
+			MountPoint: mountPoint,
+			Label:      label,
+			FSType:     fsType,
+		})
+	}
+
+	sort.Slice(drives, func(i, j int) bool {
+		return drives[i].MountPoint < drives[j].MountPoint
+	})
+	return drives
+}
+
+// isRemovableDevice reports whether a block device is on a USB bus, marked
+// removable by its driver, or an SD card
+func isRemovableDevice(device string) bool {
+	sysPath, err := filepath.EvalSymlinks(filepath.Join(sysBlockDir, filepath.Base(resolveDevice(device))))
+	if err != nil {
+		return false
+	}
+	if strings.Contains(sysPath, "/usb") {
+		return true
+	}
+
+	// Partitions inherit these attributes from the disk they are on
+	diskPath := sysPath
+	if _, err := os.Stat(filepath.Join(sysPath, "partition")); err == nil {
+		diskPath = filepath.Dir(sysPath)
+	}
+	if removable, err := readUintFile(filepath.Join(diskPath, "removable")); err == nil && removable == 1 {
+		return true
+	}
+
+	// Card readers report SD cards as non-removable mmcblk disks, like soldered eMMC storage
+	data, err := os.ReadFile(filepath.Join(diskPath, "device", "type"))
+	return err == nil && strings.TrimSpace(string(data)) == "SD"
+}
+
+// resolveDevice follows symbolic links such as /dev/disk/by-uuid/... to the device node
+func resolveDevice(device string) string {
+	if resolved, err := filepath.EvalSymlinks(device); err == nil {
+		return resolved
+	}
+	return device
+}
+
+// filesystemLabels maps device nodes to the labels udev found on their filesystems
+func filesystemLabels() map[string]string {
+	labels := make(map[string]string)
+
+	links, _ := filepath.Glob("/dev/disk/by-label/*")
+	for _, link := range links {
+		if device, err := filepath.EvalSymlinks(link); err == nil {
+			labels[device] = unescapeUdevName(filepath.Base(link))
+		}
+	}
+	return labels
+}
+
+// unescapeUdevName decodes the \x20 style escapes udev uses in /dev/disk link names
+func unescapeUdevName(name string) string {
+	var b strings.Builder
+	for n := 0; n < len(name); n++ {
+		if name[n] == '\\' && n+3 < len(name) && name[n+1] == 'x' {
+			if c, err := strconv.ParseUint(name[n+2:n+4], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				n += 3
+				continue
+			}
+		}
+		b.WriteByte(name[n])
+	}
+	return b.String()
+}
+
+// OpenPath opens a directory in the desktop's file manager without waiting for it
+func OpenPath(path string) error {
+	cmd := exec.Command("xdg-open", path)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// UnmountDrive unmounts a removable drive through udisks, which lets the
+// logged-in user unmount drives they didn't mount themselves without root
+func UnmountDrive(device string) error {
+	if _, err := runCommand(unmountTimeout, "udisksctl", "unmount", "--block-device", device); err != nil {
+		return fmt.Errorf("unmount %s: %s", device, commandError(err))
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseRemovableMounts(t *testing.T) {
+	mountinfo := `22 1 259:2 / / rw,relatime shared:1 - ext4 /dev/nvme0n1p2 rw,errors=remount-ro
+28 22 0:26 / /run rw,nosuid,nodev shared:5 - tmpfs tmpfs rw,size=1628084k
+61 22 8:17 / /media/user/BACKUP rw,nosuid,nodev,relatime shared:300 - vfat /dev/sdb1 rw,fmask=0022
+62 22 8:17 /photos /srv/photos rw,nosuid,nodev,relatime shared:300 - vfat /dev/sdb1 rw,fmask=0022
+63 22 179:1 / /media/user/My\040Card rw,nosuid,nodev,relatime shared:301 - exfat /dev/mmcblk0p1 rw
+64 22 8:33 / /mnt/data rw,relatime shared:302 - ext4 /dev/sdc1 rw
+`
+	labels := map[string]string{"/dev/sdb1": "BACKUP DRIVE", "/dev/nvme0n1p2": "root"}
+	removable := func(device string) bool {
+		return device == "/dev/sdb1" || device == "/dev/mmcblk0p1"
+	}
+
+	// Only the first mount of /dev/sdb1 is listed; the card has no label, so it is named after its mount point
+	want := []RemovableDrive{
+		{Device: "/dev/sdb1", MountPoint: "/media/user/BACKUP", Label: "BACKUP DRIVE", FSType: "vfat"},
+		{Device: "/dev/mmcblk0p1", MountPoint: "/media/user/My Card", Label: "My Card", FSType: "exfat"},
+	}
+	if got := parseRemovableMounts(mountinfo, labels, removable); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if got := parseRemovableMounts("", labels, removable); got != nil {
+		t.Errorf("got %+v from an empty table", got)
+	}
+}
+
+func TestUnescapeUdevName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"BACKUP", "BACKUP"},
+		{`My\x20Card`, "My Card"},
+		{`Data\x2fOld`, "Data/Old"},
+		{`\x20lead`, " lead"},
+		{`trail\x20`, "trail "},
+		// Not escapes
+		{`bad\xzz`, `bad\xzz`},
+		{`short\x2`, `short\x2`},
+		{`back\slash`, `back\slash`},
+	}
+
+	for _, test := range tests {
+		if got := unescapeUdevName(test.name); got != test.want {
+			t.Errorf("unescapeUdevName(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+// writeBlockDevice adds a block device to a fixture sysfs at path below root/devices,
+// linked from root/class/block like the real one, and returns its directory
+func writeBlockDevice(t *testing.T, root, path string, files map[string]string) string {
+	t.Helper()
+
+	dir := filepath.Join(root, "devices", filepath.FromSlash(path))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		writeFixture(t, dir, filepath.FromSlash(name), content)
+	}
+
+	link := filepath.Join(root, "class", "block", filepath.Base(dir))
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(dir, link); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestIsRemovableDevice(t *testing.T) {
+	root := t.TempDir()
+	previous := sysBlockDir
+	sysBlockDir = filepath.Join(root, "class", "block")
+	t.Cleanup(func() { sysBlockDir = previous })
+
+	usb := "pci0000:00/0000:00:14.0/usb2/2-1/2-1:1.0/host6/target6:0:0/6:0:0:0/block/sdb"
+	// USB SSDs don't report themselves removable, so the bus decides
+	writeBlockDevice(t, root, usb, map[string]string{"removable": "0\n"})
+	writeBlockDevice(t, root, usb+"/sdb1", map[string]string{"partition": "1\n"})
+
+	// A card reader on the SATA bus reports itself removable
+	reader := "pci0000:00/0000:00:17.0/ata3/host2/target2:0:0/2:0:0:0/block/sdc"
+	writeBlockDevice(t, root, reader, map[string]string{"removable": "1\n"})
+	writeBlockDevice(t, root, reader+"/sdc1", map[string]string{"partition": "1\n"})
+
+	// SD cards and eMMC storage are both mmcblk disks that aren't removable
+	sd := "platform/fe320000.mmc/mmc_host/mmc0/mmc0:aaaa/block/mmcblk0"
+	writeBlockDevice(t, root, sd, map[string]string{"removable": "0\n", "device/type": "SD\n"})
+	writeBlockDevice(t, root, sd+"/mmcblk0p1", map[string]string{"partition": "1\n"})
+	emmc := "platform/fe330000.mmc/mmc_host/mmc1/mmc1:0001/block/mmcblk1"
+	writeBlockDevice(t, root, emmc, map[string]string{"removable": "0\n", "device/type": "MMC\n"})
+	writeBlockDevice(t, root, emmc+"/mmcblk1p1", map[string]string{"partition": "1\n"})
+
+	nvme := "pci0000:00/0000:00:1d.0/0000:3d:00.0/nvme/nvme0/nvme0n1"
+	writeBlockDevice(t, root, nvme, map[string]string{"removable": "0\n"})
+	writeBlockDevice(t, root, nvme+"/nvme0n1p2", map[string]string{"partition": "1\n"})
+
+	tests := []struct {
+		device string
+		want   bool
+	}{
+		{"/dev/sdb", true},
+		{"/dev/sdb1", true},
+		{"/dev/sdc1", true},
+		{"/dev/mmcblk0p1", true},
+		{"/dev/mmcblk1p1", false},
+		{"/dev/nvme0n1p2", false},
+		{"/dev/sdz1", false},
+	}
+
+	for _, test := range tests {
+		if got := isRemovableDevice(test.device); got != test.want {
+			t.Errorf("isRemovableDevice(%q) = %v, want %v", test.device, got, test.want)
+		}
+	}
+}
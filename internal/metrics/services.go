@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// systemSlice is the cgroup that systemd places system services in
+const systemSlice = "system.slice"
+
+// serviceSample holds the cumulative counters of a service at the last sample
+type serviceSample struct {
+	usageUsec  uint64
+	readBytes  uint64
+	writeBytes uint64
+}
+
+var (
+	lastServiceSamples    = make(map[string]serviceSample)
+	lastServiceSampleTime time.Time
+	serviceMutex          sync.Mutex
+)
+
+// ServiceUsage contains the resource usage of a systemd service
+type ServiceUsage struct {
+	Name       string  // Unit name without the ".service" suffix
+	CPUPercent float64 // CPU usage since the previous sample, 100% = one core
+	Memory     uint64  // Bytes, from memory.current
+	ReadSpeed  float64 // Bytes per second read from storage
+	WriteSpeed float64 // Bytes per second written to storage
+}
+
+// ServiceName returns a unit name without its ".service" suffix
+func ServiceName(unit string) string {
+	return strings.TrimSuffix(unit, ".service")
+}
+
+// FindService returns the usage of a unit, given with or without its ".service"
+// suffix. Services that aren't running have no usage.
+func FindService(services []ServiceUsage, unit string) (ServiceUsage, bool) {
+	name := ServiceName(unit)
+	for _, s := range services {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return ServiceUsage{}, false
+}
+
+// GetServiceUsage returns the usage of every running system service, ordered by CPU usage.
+// Values are read from cpu.stat, memory.current and io.stat in each service's cgroup,
+// relative to the root set with SetCgroupRoot. Only services with a cgroup are running.
+func GetServiceUsage() ([]ServiceUsage, error) {
+	serviceMutex.Lock()
+	defer serviceMutex.Unlock()
+
+	entries, err := os.ReadDir(cgroupPath(systemSlice, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	timeDiff := now.Sub(lastServiceSampleTime).Seconds()
+	samples := make(map[string]serviceSample, len(entries))
+
+	var result []ServiceUsage
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasSuffix(entry.Name(), ".service") {
+			continue
+		}
+		cgroup := path.Join(systemSlice, entry.Name())
+		usage := ServiceUsage{Name: ServiceName(entry.Name())}
+
+		var sample serviceSample
+		if stat, err := readCgroupKeyed(cgroup, "cpu.stat"); err == nil {
+			sample.usageUsec = stat["usage_usec"]
+		}
+
+		// io.stat is missing when the io controller is not enabled for the slice
+		if read, written, err := readCgroupIOBytes(cgroup); err == nil {
+			sample.readBytes = read
+			sample.writeBytes = written
+		}
+		samples[usage.Name] = sample
+
+		if last, ok := lastServiceSamples[usage.Name]; ok && timeDiff > 0 {
+			if sample.usageUsec >= last.usageUsec {
+				usage.CPUPercent = float64(sample.usageUsec-last.usageUsec) / 1e6 / timeDiff * 100
+			}
+			if sample.readBytes >= last.readBytes {
+				usage.ReadSpeed = float64(sample.readBytes-last.readBytes) / timeDiff
+			}
+			if sample.writeBytes >= last.writeBytes {
+				usage.WriteSpeed = float64(sample.writeBytes-last.writeBytes) / timeDiff
+			}
+		}
+
+		if memory, err := readCgroupUint(cgroup, "memory.current"); err == nil {
+			usage.Memory = memory
+		}
+
+		result = append(result, usage)
+	}
+
+	// Update last values
+	lastServiceSamples = samples
+	lastServiceSampleTime = now
+
+	sort.Slice(result, func(a, b int) bool {
+		if result[a].CPUPercent == result[b].CPUPercent {
+			return result[a].Memory > result[b].Memory
+		}
+		return result[a].CPUPercent > result[b].CPUPercent
+	})
+
+	return result, nil
+}
@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// writeFixture writes a file below dir, creating its parent directories
+func writeFixture(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// useCgroupRoot points the cgroup collectors at a fixture tree for the rest of the test
+func useCgroupRoot(t *testing.T, root string) {
+	t.Helper()
+
+	cgroupMutex.Lock()
+	previous := cgroupRoot
+	cgroupMutex.Unlock()
+
+	SetCgroupRoot(root)
+	t.Cleanup(func() {
+		cgroupMutex.Lock()
+		cgroupRoot = previous
+		cgroupMutex.Unlock()
+	})
+}
+
+// writeService writes the counters of a service in a fixture cgroup tree
+func writeService(t *testing.T, root, unit string, usageUsec, memory, readBytes, writeBytes uint64) {
+	t.Helper()
+
+	dir := filepath.Join(systemSlice, unit)
+	writeFixture(t, root, filepath.Join(dir, "cpu.stat"),
+		"usage_usec "+itoa(usageUsec)+"\nuser_usec 0\nsystem_usec 0\n")
+	writeFixture(t, root, filepath.Join(dir, "memory.current"), itoa(memory)+"\n")
+
+	// Reads and writes are split across two devices, to check they are added up
+	writeFixture(t, root, filepath.Join(dir, "io.stat"),
+		"8:0 rbytes="+itoa(readBytes/2)+" wbytes="+itoa(writeBytes/2)+" rios=1 wios=1 dbytes=0 dios=0\n"+
+			"259:0 rbytes="+itoa(readBytes-readBytes/2)+" wbytes="+itoa(writeBytes-writeBytes/2)+" rios=1 wios=1 dbytes=0 dios=0\n")
+}
+
+// itoa formats a counter for a fixture file
+func itoa(n uint64) string {
+	return strconv.FormatUint(n, 10)
+}
+
+func TestGetServiceUsage(t *testing.T) {
+	root := t.TempDir()
+	useCgroupRoot(t, root)
+
+	writeService(t, root, "postgresql.service", 1000000, 512*1024*1024, 1000, 2000)
+	writeService(t, root, "rabbitmq-server.service", 5000000, 256*1024*1024, 0, 0)
+	// Slices and scopes below system.slice are not services
+	writeFixture(t, root, filepath.Join(systemSlice, "system-getty.slice", "memory.current"), "1024\n")
+
+	serviceMutex.Lock()
+	lastServiceSamples = make(map[string]serviceSample)
+	serviceMutex.Unlock()
+
+	// The first sample has nothing to compare against, so only memory is known
+	services, err := GetServiceUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 2 {
+		t.Fatalf("got %d services, want 2: %+v", len(services), services)
+	}
+	for _, s := range services {
+		if s.CPUPercent != 0 || s.ReadSpeed != 0 || s.WriteSpeed != 0 {
+			t.Errorf("first sample of %s has rates: %+v", s.Name, s)
+		}
+	}
+	if s, _ := FindService(services, "postgresql"); s.Memory != 512*1024*1024 {
+		t.Errorf("postgresql memory = %d, want %d", s.Memory, 512*1024*1024)
+	}
+
+	// Two seconds later, postgres used half a core and read 1 MB while rabbitmq idled
+	writeService(t, root, "postgresql.service", 2000000, 512*1024*1024, 1000+2000000, 2000+4000000)
+	writeService(t, root, "rabbitmq-server.service", 5000000, 256*1024*1024, 0, 0)
+	serviceMutex.Lock()
+	lastServiceSampleTime = time.Now().Add(-2 * time.Second)
+	serviceMutex.Unlock()
+
+	services, err = GetServiceUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 2 || services[0].Name != "postgresql" {
+		t.Fatalf("services are not ordered by CPU usage: %+v", services)
+	}
+
+	postgres := services[0]
+	checkNear(t, "postgresql CPU%", postgres.CPUPercent, 50)
+	checkNear(t, "postgresql read speed", postgres.ReadSpeed, 1000000)
+	checkNear(t, "postgresql write speed", postgres.WriteSpeed, 2000000)
+
+	rabbitmq := services[1]
+	if rabbitmq.CPUPercent != 0 || rabbitmq.ReadSpeed != 0 || rabbitmq.WriteSpeed != 0 {
+		t.Errorf("idle rabbitmq has rates: %+v", rabbitmq)
+	}
+}
+
+func TestFindPinnedService(t *testing.T) {
+	root := t.TempDir()
+	useCgroupRoot(t, root)
+
+	writeService(t, root, "postgresql.service", 1000, 4096, 0, 0)
+	// A unit without memory.current or io.stat is still running
+	writeFixture(t, root, filepath.Join(systemSlice, "redis-server.service", "cpu.stat"), "usage_usec 10\n")
+
+	services, err := GetServiceUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, unit := range []string{"postgresql.service", "postgresql", "redis-server.service"} {
+		if _, ok := FindService(services, unit); !ok {
+			t.Errorf("pinned service %s not found", unit)
+		}
+	}
+	if s, ok := FindService(services, "mosquitto.service"); ok {
+		t.Errorf("stopped service found: %+v", s)
+	}
+}
+
+func TestGetServiceUsageMissingSlice(t *testing.T) {
+	useCgroupRoot(t, t.TempDir())
+
+	if _, err := GetServiceUsage(); err == nil {
+		t.Error("no error without a system.slice cgroup")
+	}
+}
+
+// checkNear fails the test if got is more than 1% away from want
+func checkNear(t *testing.T, name string, got, want float64) {
+	t.Helper()
+
+	if math.Abs(got-want) > math.Abs(want)*0.01 {
+		t.Errorf("%s = %.2f, want %.2f", name, got, want)
+	}
+}
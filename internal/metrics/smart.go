@@ -0,0 +1,162 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// smartctlTimeout bounds a single smartctl run; a drive that is spun down or
+// failing can make it hang for a long time
+var smartctlTimeout = 30 * time.Second
+
+// DriveHealth is the SMART health of a drive as reported by smartctl
+type DriveHealth struct {
+	Device             string
+	Model              string
+	Passed             bool      // Overall SMART self-assessment
+	Temperature        int       // Celsius, 0 if not reported
+	PercentageUsed     int       // Estimated wear of an SSD, -1 if not reported
+	ReallocatedSectors int64     // Sectors remapped by the drive, -1 if not reported
+	Error              string    // Why the drive could not be checked, if it couldn't
+	Checked            time.Time // When smartctl was run
+}
+
+// Healthy reports whether the drive was checked, passed its self-assessment and has no reallocated sectors
+func (d DriveHealth) Healthy() bool {
+	return d.Error == "" && d.Passed && d.ReallocatedSectors <= 0
+}
+
+var (
+	driveHealthResults []DriveHealth
+	driveHealthChecked time.Time
+	driveHealthRunning bool
+	driveHealthMutex   sync.Mutex
+)
+
+// smartctlOutput is the subset of `smartctl --json -a` used here
+type smartctlOutput struct {
+	Smartctl struct {
+		ExitStatus int `json:"exit_status"`
+		Messages   []struct {
+			String   string `json:"string"`
+			Severity string `json:"severity"`
+		} `json:"messages"`
+	} `json:"smartctl"`
+	ModelName   string `json:"model_name"`
+	SmartStatus *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	Temperature struct {
+		Current int `json:"current"`
+	} `json:"temperature"`
+	NVMeHealth *struct {
+		PercentageUsed int `json:"percentage_used"`
+	} `json:"nvme_smart_health_information_log"`
+	ATAAttributes struct {
+		Table []struct {
+			ID    int `json:"id"`
+			Value int `json:"value"`
+			Raw   struct {
+				Value int64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+}
+
+// GetDriveHealth returns the latest smartctl results for devices. Once they are
+// older than interval a refresh is started in the background, so the call never
+// waits for smartctl; the new results are returned by a later call. Before the
+// first run has finished nil is returned.
+func GetDriveHealth(command string, devices []string, interval time.Duration) []DriveHealth {
+	driveHealthMutex.Lock()
+	defer driveHealthMutex.Unlock()
+
+	if !driveHealthRunning && time.Since(driveHealthChecked) >= interval {
+		driveHealthRunning = true
+		go refreshDriveHealth(command, devices)
+	}
+
+	return driveHealthResults
+}
+
+// refreshDriveHealth runs smartctl on each device in turn and stores the results
+func refreshDriveHealth(command string, devices []string) {
+	var results []DriveHealth
+	for _, device := range devices {
+		results = append(results, checkDrive(command, device))
+	}
+
+	driveHealthMutex.Lock()
+	defer driveHealthMutex.Unlock()
+
+	driveHealthResults = results
+	driveHealthChecked = time.Now()
+	driveHealthRunning = false
+}
+
+// checkDrive runs smartctl on a device and parses its JSON report
+func checkDrive(command, device string) DriveHealth {
+	health := DriveHealth{
+		Device:             device,
+		PercentageUsed:     -1,
+		ReallocatedSectors: -1,
+		Checked:            time.Now(),
+	}
+
+	output, err := runCommand(smartctlTimeout, command, "--json", "-a", device)
+	if errors.Is(err, ErrCommandTimeout) {
+		health.Error = fmt.Sprintf("smartctl timed out after %s", smartctlTimeout)
+		return health
+	}
+
+	// smartctl's exit status is a bit mask; bits above 1 describe the drive's
+	// health rather than a failure to run, and the report is still complete
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		health.Error = err.Error()
+		return health
+	}
+
+	var report smartctlOutput
+	if err := json.Unmarshal(output, &report); err != nil {
+		health.Error = fmt.Sprintf("unreadable smartctl output: %v", err)
+		return health
+	}
+
+	if report.Smartctl.ExitStatus&0x3 != 0 || report.SmartStatus == nil {
+		health.Error = "smartctl could not read the device"
+		for _, msg := range report.Smartctl.Messages {
+			if msg.Severity == "error" {
+				health.Error = msg.String
+				break
+			}
+		}
+		return health
+	}
+
+	health.Model = report.ModelName
+	health.Passed = report.SmartStatus.Passed
+	health.Temperature = report.Temperature.Current
+
+	if report.NVMeHealth != nil {
+		health.PercentageUsed = report.NVMeHealth.PercentageUsed
+	}
+
+	for _, attr := range report.ATAAttributes.Table {
+		switch attr.ID {
+		case 5: // Reallocated_Sector_Ct
+			health.ReallocatedSectors = attr.Raw.Value
+		case 177, 231, 233: // Wear_Leveling_Count, SSD_Life_Left, Media_Wearout_Indicator
+			// These count down from 100 as the drive wears
+			if health.PercentageUsed < 0 && attr.Value <= 100 {
+				health.PercentageUsed = 100 - attr.Value
+			}
+		}
+	}
+
+	return health
+}
@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubSmartctl writes a script that prints report and exits with status, in place of smartctl
+func stubSmartctl(t *testing.T, report string, status int) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "smartctl")
+	script := fmt.Sprintf("#!/bin/sh\ncat <<'EOF'\n%s\nEOF\nexit %d\n", report, status)
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCheckDrive(t *testing.T) {
+	tests := []struct {
+		name    string
+		report  string
+		status  int
+		want    DriveHealth // Compared without Device, Checked and Error
+		error   string      // Start of the expected error
+		healthy bool
+	}{
+		{
+			name: "passed",
+			report: `{"smartctl": {"exit_status": 0}, "model_name": "WDC WD40EFRX",
+				"smart_status": {"passed": true}, "temperature": {"current": 34},
+				"ata_smart_attributes": {"table": [
+					{"id": 5, "value": 200, "raw": {"value": 0}},
+					{"id": 177, "value": 93, "raw": {"value": 412}}]}}`,
+			want:    DriveHealth{Model: "WDC WD40EFRX", Passed: true, Temperature: 34, PercentageUsed: 7},
+			healthy: true,
+		},
+		{
+			// Bit 3: the drive's self-assessment says it is failing
+			name: "failing",
+			report: `{"smartctl": {"exit_status": 8}, "model_name": "ST2000DM001",
+				"smart_status": {"passed": false}, "temperature": {"current": 51},
+				"ata_smart_attributes": {"table": [{"id": 5, "value": 1, "raw": {"value": 3120}}]}}`,
+			status: 8,
+			want:   DriveHealth{Model: "ST2000DM001", Temperature: 51, PercentageUsed: -1, ReallocatedSectors: 3120},
+		},
+		{
+			// Bit 6: the drive's error log has entries, but the report is complete
+			name: "error log bit",
+			report: `{"smartctl": {"exit_status": 64}, "model_name": "ST2000DM001",
+				"smart_status": {"passed": true},
+				"ata_smart_attributes": {"table": [{"id": 5, "value": 100, "raw": {"value": 8}}]}}`,
+			status: 64,
+			want:   DriveHealth{Model: "ST2000DM001", Passed: true, PercentageUsed: -1, ReallocatedSectors: 8},
+		},
+		{
+			// Bit 1: the device could not be opened, so there is nothing to report
+			name: "open failed",
+			report: `{"smartctl": {"exit_status": 2, "messages": [
+				{"string": "Smartctl open device: /dev/sdz failed: No such device", "severity": "error"}]}}`,
+			status: 2,
+			want:   DriveHealth{PercentageUsed: -1, ReallocatedSectors: -1},
+			error:  "Smartctl open device: /dev/sdz failed: No such device",
+		},
+		{
+			name: "NVMe wear",
+			report: `{"smartctl": {"exit_status": 0}, "model_name": "Samsung SSD 980 PRO",
+				"smart_status": {"passed": true}, "temperature": {"current": 45},
+				"nvme_smart_health_information_log": {"percentage_used": 12}}`,
+			want:    DriveHealth{Model: "Samsung SSD 980 PRO", Passed: true, Temperature: 45, PercentageUsed: 12, ReallocatedSectors: -1},
+			healthy: true,
+		},
+		{
+			name:   "unreadable output",
+			report: "smartctl 7.4: unknown option --json",
+			status: 1,
+			want:   DriveHealth{PercentageUsed: -1, ReallocatedSectors: -1},
+			error:  "unreadable smartctl output",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			health := checkDrive(stubSmartctl(t, test.report, test.status), "/dev/sda")
+
+			if health.Checked.IsZero() || health.Device != "/dev/sda" {
+				t.Errorf("device %q checked at %s", health.Device, health.Checked)
+			}
+			if !strings.HasPrefix(health.Error, test.error) || (test.error == "") != (health.Error == "") {
+				t.Errorf("error = %q, want %q", health.Error, test.error)
+			}
+			if health.Healthy() != test.healthy {
+				t.Errorf("Healthy() = %v, want %v", health.Healthy(), test.healthy)
+			}
+			health.Device, health.Checked, health.Error = "", time.Time{}, ""
+			if health != test.want {
+				t.Errorf("got %+v, want %+v", health, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckDriveTimeout(t *testing.T) {
+	previous := smartctlTimeout
+	smartctlTimeout = 200 * time.Millisecond
+	t.Cleanup(func() { smartctlTimeout = previous })
+
+	path := filepath.Join(t.TempDir(), "smartctl")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nsleep 10\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	health := checkDrive(path, "/dev/sda")
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("checkDrive waited %s for a hung smartctl", elapsed)
+	}
+	if want := "smartctl timed out after 200ms"; health.Error != want {
+		t.Errorf("error = %q, want %q", health.Error, want)
+	}
+	if health.Healthy() {
+		t.Error("a drive that wasn't checked is healthy")
+	}
+}
+
+func TestCheckDriveMissingSmartctl(t *testing.T) {
+	health := checkDrive(filepath.Join(t.TempDir(), "smartctl"), "/dev/sda")
+	if health.Error == "" || health.Healthy() {
+		t.Errorf("got %+v, want an error", health)
+	}
+}
+
+func TestGetDriveHealth(t *testing.T) {
+	smartctl := stubSmartctl(t, `{"smartctl": {"exit_status": 0}, "smart_status": {"passed": true}}`, 0)
+
+	driveHealthMutex.Lock()
+	driveHealthResults, driveHealthChecked, driveHealthRunning = nil, time.Time{}, false
+	driveHealthMutex.Unlock()
+
+	// smartctl runs in the background, so the first call has no results
+	if results := GetDriveHealth(smartctl, []string{"/dev/sda", "/dev/nvme0"}, time.Hour); results != nil {
+		t.Fatalf("first call returned %+v", results)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	var results []DriveHealth
+	for results == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		results = GetDriveHealth(smartctl, []string{"/dev/sda", "/dev/nvme0"}, time.Hour)
+	}
+	if len(results) != 2 || results[0].Device != "/dev/sda" || results[1].Device != "/dev/nvme0" {
+		t.Fatalf("got %+v, want both devices in order", results)
+	}
+	for _, health := range results {
+		if !health.Healthy() {
+			t.Errorf("%s is not healthy: %+v", health.Device, health)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package metrics
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RAIDArray is the state of a Linux software RAID (md) array
+type RAIDArray struct {
+	Name          string // e.g. "md0"
+	Level         string // e.g. "raid1"
+	Active        bool
+	Devices       int      // Member devices the array should have
+	ActiveDevices int      // Member devices currently in sync
+	Failed        []string // Member devices marked faulty
+	SyncAction    string   // "resync", "recovery", "check" or "reshape" while in progress
+	SyncProgress  float64  // Percentage of SyncAction completed
+	SyncFinish    string   // Estimated time remaining, as reported by the kernel, e.g. "75.3min"
+}
+
+// Degraded reports whether the array is running without all of its devices
+func (a RAIDArray) Degraded() bool {
+	return !a.Active || a.ActiveDevices < a.Devices || len(a.Failed) > 0
+}
+
+// ReadOnlyMount is a filesystem on a block device that is mounted read-only,
+// typically because the kernel remounted it after an I/O error
+type ReadOnlyMount struct {
+	Device     string
+	MountPoint string
+	FSType     string
+}
+
+// StorageHealth contains software RAID state and read-only filesystems
+type StorageHealth struct {
+	Arrays   []RAIDArray
+	ReadOnly []ReadOnlyMount
+}
+
+// Problems returns the number of degraded arrays and read-only filesystems
+func (h StorageHealth) Problems() int {
+	count := len(h.ReadOnly)
+	for _, a := range h.Arrays {
+		if a.Degraded() {
+			count++
+		}
+	}
+	return count
+}
+
+// Filesystems that are read-only by design, such as snap packages and optical media
+var readOnlyFSTypes = map[string]bool{
+	"squashfs": true,
+	"iso9660":  true,
+	"udf":      true,
+	"erofs":    true,
+	"cramfs":   true,
+	"romfs":    true,
+}
+
+var (
+	// Matches "[2/1] [U_]"
+	mdDevicesPattern = regexp.MustCompile(`\[(\d+)/(\d+)\]`)
+	// Matches "resync =  8.5% (83062720/976630464) finish=75.3min"
+	mdSyncPattern = regexp.MustCompile(`(resync|recovery|check|reshape)\s*=\s*([\d.]+)%.*?finish=(\S+)`)
+)
+
+// GetStorageHealth returns the state of md arrays and any block device filesystems mounted read-only
+func GetStorageHealth() (StorageHealth, error) {
+	health := StorageHealth{}
+
+	// Without the md driver loaded there is no mdstat and nothing to report
+	arrays, err := parseMDStat("/proc/mdstat")
+	if err != nil && !os.IsNotExist(err) {
+		return health, err
+	}
+	health.Arrays = arrays
+
+	health.ReadOnly, err = findReadOnlyMounts("/proc/self/mountinfo")
+	if err != nil {
+		return health, err
+	}
+
+	return health, nil
+}
+
+// parseMDStat parses the arrays listed in /proc/mdstat
+func parseMDStat(path string) ([]RAIDArray, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var arrays []RAIDArray
+	var current *RAIDArray
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+
+		// An array starts with "md0 : active raid1 sdb1[1] sda1[0](F)"
+		if len(fields) >= 3 && strings.HasPrefix(fields[0], "md") && fields[1] == ":" {
+			arrays = append(arrays, RAIDArray{Name: fields[0], Active: fields[2] == "active"})
+			current = &arrays[len(arrays)-1]
+
+			// States such as "(auto-read-only)" come before the level
+			members := fields[3:]
+			for len(members) > 0 && strings.HasPrefix(members[0], "(") {
+				members = members[1:]
+			}
+			if current.Active && len(members) > 0 && !strings.Contains(members[0], "[") {
+				current.Level = members[0]
+				members = members[1:]
+			}
+			for _, member := range members {
+				if bracket := strings.Index(member, "["); bracket > 0 && strings.HasSuffix(member, "(F)") {
+					current.Failed = append(current.Failed, member[:bracket])
+				}
+			}
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		// Continuation lines hold the device counts and any sync in progress
+		if m := mdDevicesPattern.FindStringSubmatch(line); m != nil && current.Devices == 0 {
+			current.Devices, _ = strconv.Atoi(m[1])
+			current.ActiveDevices, _ = strconv.Atoi(m[2])
+		}
+		if m := mdSyncPattern.FindStringSubmatch(line); m != nil {
+			current.SyncAction = m[1]
+			current.SyncProgress, _ = strconv.ParseFloat(m[2], 64)
+			current.SyncFinish = m[3]
+		}
+		if line == "" {
+			current = nil
+		}
+	}
+
+	return arrays, scanner.Err()
+}
+
+// findReadOnlyMounts returns block device filesystems whose superblock is read-only.
+// Read-only bind mounts of writable filesystems are deliberate and are not reported.
+func findReadOnlyMounts(path string) ([]ReadOnlyMount, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var mounts []ReadOnlyMount
+	seen := make(map[string]bool)
+
+	// Lines look like "36 35 98:0 / /mnt rw,noatime master:1 - ext3 /dev/sda1 ro,errors=continue"
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		sep := -1
+		for n, field := range fields {
+			if field == "-" {
+				sep = n
+				break
+			}
+		}
+		if sep < 5 || len(fields) < sep+4 {
+			continue
+		}
+
+		fsType, device, superOptions := fields[sep+1], fields[sep+2], fields[sep+3]
+		if !strings.HasPrefix(device, "/dev/") || readOnlyFSTypes[fsType] || seen[device] {
+			continue
+		}
+
+		for _, option := range strings.Split(superOptions, ",") {
+			if option == "ro" {
+				seen[device] = true
+				mounts = append(mounts, ReadOnlyMount{
+					Device:     device,
+					MountPoint: unescapeMountPath(fields[4]),
+					FSType:     fsType,
+				})
+				break
+			}
+		}
+	}
+
+	return mounts, scanner.Err()
+}
+
+// unescapeMountPath decodes the octal escapes used for spaces and tabs in mountinfo paths
+func unescapeMountPath(path string) string {
+	if !strings.Contains(path, `\`) {
+		return path
+	}
+
+	var b strings.Builder
+	for n := 0; n < len(path); n++ {
+		if path[n] == '\\' && n+3 < len(path) {
+			if c, err := strconv.ParseUint(path[n+1:n+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				n += 3
+				continue
+			}
+		}
+		b.WriteByte(path[n])
+	}
+	return b.String()
+}
@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseMDStat(t *testing.T) {
+	tests := []struct {
+		name   string
+		mdstat string
+		want   []RAIDArray
+	}{
+		{
+			name: "healthy",
+			mdstat: `Personalities : [raid1] [raid6] [raid5] [raid4]
+md0 : active raid1 sdb1[1] sda1[0]
+      976630464 blocks super 1.2 [2/2] [UU]
+      bitmap: 0/8 pages [0KB], 65536KB chunk
+
+unused devices: <none>
+`,
+			want: []RAIDArray{{Name: "md0", Level: "raid1", Active: true, Devices: 2, ActiveDevices: 2}},
+		},
+		{
+			name: "failed member",
+			mdstat: `Personalities : [raid1]
+md0 : active raid1 sdb1[1](F) sda1[0]
+      976630464 blocks super 1.2 [2/1] [U_]
+
+unused devices: <none>
+`,
+			want: []RAIDArray{{Name: "md0", Level: "raid1", Active: true, Devices: 2, ActiveDevices: 1, Failed: []string{"sdb1"}}},
+		},
+		{
+			name: "rebuilding",
+			mdstat: `Personalities : [raid5]
+md1 : active raid5 sdd1[3] sdc1[2] sdb1[1] sda1[0]
+      2929890816 blocks super 1.2 level 5, 512k chunk, algorithm 2 [4/3] [UUU_]
+      [=>...................]  recovery =  8.5% (83062720/976630272) finish=75.3min speed=197632K/sec
+
+unused devices: <none>
+`,
+			want: []RAIDArray{{Name: "md1", Level: "raid5", Active: true, Devices: 4, ActiveDevices: 3,
+				SyncAction: "recovery", SyncProgress: 8.5, SyncFinish: "75.3min"}},
+		},
+		{
+			// Arrays assembled at boot stay read-only until first written to
+			name: "auto-read-only",
+			mdstat: `Personalities : [raid1]
+md127 : active (auto-read-only) raid1 sda1[0] sdb1[1]
+      976630464 blocks super 1.2 [2/2] [UU]
+
+unused devices: <none>
+`,
+			want: []RAIDArray{{Name: "md127", Level: "raid1", Active: true, Devices: 2, ActiveDevices: 2}},
+		},
+		{
+			// An array that couldn't be assembled lists its members as spares, without a level
+			name: "inactive",
+			mdstat: `Personalities :
+md127 : inactive sdb1[1](S) sda1[0](S)
+      1953260928 blocks super 1.2
+
+unused devices: <none>
+`,
+			want: []RAIDArray{{Name: "md127"}},
+		},
+		{
+			name: "several arrays",
+			mdstat: `Personalities : [raid1] [raid0]
+md1 : active raid0 sdd1[1] sdc1[0]
+      1953260544 blocks super 1.2 512k chunks
+
+md0 : active raid1 sdb1[1] sda1[0]
+      976630464 blocks super 1.2 [2/2] [UU]
+
+unused devices: <none>
+`,
+			want: []RAIDArray{
+				{Name: "md1", Level: "raid0", Active: true},
+				{Name: "md0", Level: "raid1", Active: true, Devices: 2, ActiveDevices: 2},
+			},
+		},
+		{
+			name:   "no arrays",
+			mdstat: "Personalities :\nunused devices: <none>\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFixture(t, dir, "mdstat", test.mdstat)
+
+			arrays, err := parseMDStat(filepath.Join(dir, "mdstat"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(arrays, test.want) {
+				t.Errorf("got %+v, want %+v", arrays, test.want)
+			}
+		})
+	}
+}
+
+func TestRAIDArrayDegraded(t *testing.T) {
+	tests := []struct {
+		array RAIDArray
+		want  bool
+	}{
+		{RAIDArray{Active: true, Devices: 2, ActiveDevices: 2}, false},
+		{RAIDArray{Active: true, Devices: 2, ActiveDevices: 1}, true},
+		{RAIDArray{Active: true, Devices: 2, ActiveDevices: 2, Failed: []string{"sdc1"}}, true},
+		{RAIDArray{}, true},
+	}
+
+	for _, test := range tests {
+		if got := test.array.Degraded(); got != test.want {
+			t.Errorf("%+v: Degraded() = %v, want %v", test.array, got, test.want)
+		}
+	}
+}
+
+func TestFindReadOnlyMounts(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "mountinfo", `22 1 259:2 / / rw,relatime shared:1 - ext4 /dev/nvme0n1p2 rw,errors=remount-ro
+36 22 8:17 / /mnt/backup ro,relatime shared:30 - ext4 /dev/sdb1 ro,errors=remount-ro
+37 22 8:17 / /mnt/backup\040copy ro,relatime shared:30 - ext4 /dev/sdb1 ro,errors=remount-ro
+38 22 8:33 / /media/My\040Disk ro,nosuid,nodev shared:31 master:4 - xfs /dev/sdc1 ro,attr2,inode64
+40 22 259:2 /srv /srv/readonly ro,relatime shared:1 - ext4 /dev/nvme0n1p2 rw,errors=remount-ro
+41 22 7:3 / /snap/core22/1380 ro,nodev,relatime shared:33 - squashfs /dev/loop3 ro,errors=continue
+42 22 11:0 / /media/cdrom ro,nosuid,nodev - iso9660 /dev/sr0 ro,nojoliet
+43 22 0:45 / /run/credentials ro,nosuid,nodev shared:20 - tmpfs tmpfs ro,size=1024k
+44 22 0:46 / /sys/fs/pstore rw,nosuid - pstore pstore rw
+`)
+
+	mounts, err := findReadOnlyMounts(filepath.Join(dir, "mountinfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The bind mount of the root, the snap and the CD are read-only by choice;
+	// tmpfs isn't on a block device; /dev/sdb1 is reported once
+	want := []ReadOnlyMount{
+		{Device: "/dev/sdb1", MountPoint: "/mnt/backup", FSType: "ext4"},
+		{Device: "/dev/sdc1", MountPoint: "/media/My Disk", FSType: "xfs"},
+	}
+	if !reflect.DeepEqual(mounts, want) {
+		t.Errorf("got %+v, want %+v", mounts, want)
+	}
+}
+
+func TestUnescapeMountPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/mnt/backup", "/mnt/backup"},
+		{`/media/My\040Disk`, "/media/My Disk"},
+		{`/mnt/a\011b`, "/mnt/a\tb"},
+		{`/mnt/back\134slash`, `/mnt/back\slash`},
+		{`/mnt/end\040`, "/mnt/end "},
+		// Not an escape
+		{`/mnt/odd\04`, `/mnt/odd\04`},
+	}
+
+	for _, test := range tests {
+		if got := unescapeMountPath(test.path); got != test.want {
+			t.Errorf("unescapeMountPath(%q) = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
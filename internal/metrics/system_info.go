@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// SystemInfo contains the identity of the machine and who is logged in to it
+type SystemInfo struct {
+	Hostname string
+	Kernel   string
+	Distro   string // PRETTY_NAME from /etc/os-release
+	Uptime   time.Duration
+	BootTime time.Time
+	Sessions []LoginSession
+	Clock    *ClockSync // nil if the clock state could not be read
+}
+
+// LoginSession is an active login read from utmp
+type LoginSession struct {
+	User     string
+	Terminal string
+	Host     string // Remote address for SSH sessions, or the X display for local ones
+	Started  time.Time
+}
+
+// Remote reports whether the session was opened over the network
+func (s LoginSession) Remote() bool {
+	// Local graphical sessions record their display, e.g. ":0"
+	return s.Host != "" && !strings.HasPrefix(s.Host, ":")
+}
+
+// GetSystemInfo returns the machine's identity, uptime and active login sessions
+func GetSystemInfo() (SystemInfo, error) {
+	info := SystemInfo{}
+
+	hostInfo, err := host.Info()
+	if err != nil {
+		return info, err
+	}
+
+	info.Hostname = hostInfo.Hostname
+	info.Kernel = hostInfo.KernelVersion
+	info.Uptime = time.Duration(hostInfo.Uptime) * time.Second
+	info.BootTime = time.Unix(int64(hostInfo.BootTime), 0)
+
+	if clock, err := GetClockSync(); err == nil {
+		info.Clock = &clock
+	}
+
+	info.Distro = readOSRelease("/etc/os-release")["PRETTY_NAME"]
+	if info.Distro == "" {
+		info.Distro = strings.TrimSpace(hostInfo.Platform + " " + hostInfo.PlatformVersion)
+	}
+
+	// Sessions are parsed from utmp, which may be missing on minimal systems
+	users, err := host.Users()
+	if err != nil && !os.IsNotExist(err) {
+		return info, err
+	}
+
+	for _, u := range users {
+		info.Sessions = append(info.Sessions, LoginSession{
+			User:     u.User,
+			Terminal: u.Terminal,
+			Host:     u.Host,
+			Started:  time.Unix(int64(u.Started), 0),
+		})
+	}
+
+	return info, nil
+}
+
+// readOSRelease parses the KEY=value pairs of an os-release file
+func readOSRelease(path string) map[string]string {
+	values := make(map[string]string)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return values
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			continue
+		}
+
+		values[line[:eq]] = strings.Trim(line[eq+1:], `"'`)
+	}
+
+	return values
+}
@@ -0,0 +1,31 @@
+package metrics
+
+// AlertLevel says how far a value is past a metric's thresholds
+type AlertLevel int
+
+const (
+	LevelOK AlertLevel = iota
+	LevelWarning
+	LevelCritical
+)
+
+// Thresholds are the values past which a metric is flagged. Unset thresholds are nil.
+type Thresholds struct {
+	WarnAbove     *float64
+	CriticalAbove *float64
+	WarnBelow     *float64
+	CriticalBelow *float64
+}
+
+// Level returns how far value is past the thresholds
+func (t Thresholds) Level(value float64) AlertLevel {
+	switch {
+	case t.CriticalAbove != nil && value > *t.CriticalAbove,
+		t.CriticalBelow != nil && value < *t.CriticalBelow:
+		return LevelCritical
+	case t.WarnAbove != nil && value > *t.WarnAbove,
+		t.WarnBelow != nil && value < *t.WarnBelow:
+		return LevelWarning
+	}
+	return LevelOK
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+const cpuSysfsRoot = "/sys/devices/system/cpu"
+
+var (
+	lastCoreThrottles    map[string]uint64 // core_throttle_count by CPU
+	lastPackageThrottles map[uint64]uint64 // package_throttle_count by physical package
+	throttleMutex        sync.Mutex
+)
+
+// ThrottleStatus describes whether the CPU is being slowed down by heat or power limits
+type ThrottleStatus struct {
+	Throttled      bool    // The throttle counters increased since the previous call
+	CoreEvents     uint64  // Core throttling events since the previous call, summed across CPUs
+	PackageEvents  uint64  // Package throttling events since the previous call, summed across packages
+	CurrentMHz     float64 // Average current frequency across CPUs
+	MaxMHz         float64 // Highest frequency the CPUs support
+	FrequencyKnown bool    // False when cpufreq is not available, e.g. in a VM
+}
+
+// FrequencyPercent returns the average current frequency as a percentage of the maximum
+func (t ThrottleStatus) FrequencyPercent() float64 {
+	if t.MaxMHz == 0 {
+		return 0
+	}
+	return t.CurrentMHz / t.MaxMHz * 100
+}
+
+// GetThrottleStatus reads the thermal throttle counters and CPU frequencies.
+// Throttled is set when a counter increased since the previous call, so the
+// first call never reports throttling. The counters are only provided by Intel CPUs.
+func GetThrottleStatus() (ThrottleStatus, error) {
+	throttleMutex.Lock()
+	defer throttleMutex.Unlock()
+
+	cpus, err := filepath.Glob(filepath.Join(cpuSysfsRoot, "cpu[0-9]*"))
+	if err != nil || len(cpus) == 0 {
+		return ThrottleStatus{}, fmt.Errorf("no CPUs found in %s", cpuSysfsRoot)
+	}
+
+	status := ThrottleStatus{}
+	coreCounts := make(map[string]uint64)
+	packageCounts := make(map[uint64]uint64)
+	var totalMHz float64
+	var freqCPUs int
+
+	for _, dir := range cpus {
+		if count, err := readUintFile(filepath.Join(dir, "thermal_throttle", "core_throttle_count")); err == nil {
+			coreCounts[dir] = count
+		}
+
+		// Every CPU in a package reports the same package counter, so count it once per package
+		if count, err := readUintFile(filepath.Join(dir, "thermal_throttle", "package_throttle_count")); err == nil {
+			pkg, _ := readUintFile(filepath.Join(dir, "topology", "physical_package_id"))
+			packageCounts[pkg] = count
+		}
+
+		// Frequencies are reported in kHz; offline CPUs have no cpufreq directory
+		curFreq, curErr := readUintFile(filepath.Join(dir, "cpufreq", "scaling_cur_freq"))
+		maxFreq, maxErr := readUintFile(filepath.Join(dir, "cpufreq", "cpuinfo_max_freq"))
+		if curErr == nil && maxErr == nil {
+			totalMHz += float64(curFreq) / 1000
+			if float64(maxFreq)/1000 > status.MaxMHz {
+				status.MaxMHz = float64(maxFreq) / 1000
+			}
+			freqCPUs++
+		}
+	}
+
+	if freqCPUs > 0 {
+		status.CurrentMHz = totalMHz / float64(freqCPUs)
+		status.FrequencyKnown = true
+	}
+
+	if lastCoreThrottles != nil {
+		for cpu, count := range coreCounts {
+			if last, ok := lastCoreThrottles[cpu]; ok && count > last {
+				status.CoreEvents += count - last
+			}
+		}
+		for pkg, count := range packageCounts {
+			if last, ok := lastPackageThrottles[pkg]; ok && count > last {
+				status.PackageEvents += count - last
+			}
+		}
+	}
+	status.Throttled = status.CoreEvents > 0 || status.PackageEvents > 0
+
+	// Update last values
+	lastCoreThrottles = coreCounts
+	lastPackageThrottles = packageCounts
+
+	return status, nil
+}
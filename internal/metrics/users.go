@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"os/user"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+var (
+	lastUserCPUTimes   = make(map[int32]float64)
+	lastUserSampleTime time.Time
+	usernames          = make(map[uint32]string)
+	userMutex          sync.Mutex
+)
+
+// UserUsage contains the combined resource usage of all processes owned by a user
+type UserUsage struct {
+	UID          uint32
+	Username     string
+	CPUPercent   float64 // Combined CPU usage, 100% = one core
+	MemoryRSS    uint64  // Combined resident memory in bytes
+	ProcessCount int
+}
+
+// GetUserUsage returns resource usage aggregated by the real UID of each process,
+// ordered by CPU usage. CPU usage is computed from the change since the previous call.
+func GetUserUsage() ([]UserUsage, error) {
+	userMutex.Lock()
+	defer userMutex.Unlock()
+
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	timeDiff := now.Sub(lastUserSampleTime).Seconds()
+	cpuTimes := make(map[int32]float64, len(procs))
+	byUID := make(map[uint32]*UserUsage)
+
+	for _, p := range procs {
+		// The first UID in /proc/[pid]/status is the real UID
+		uids, err := p.Uids()
+		if err != nil || len(uids) == 0 {
+			continue
+		}
+		uid := uint32(uids[0])
+
+		usage, ok := byUID[uid]
+		if !ok {
+			usage = &UserUsage{UID: uid, Username: lookupUsername(uid)}
+			byUID[uid] = usage
+		}
+		usage.ProcessCount++
+
+		if times, err := p.Times(); err == nil {
+			total := times.User + times.System
+			cpuTimes[p.Pid] = total
+			if last, ok := lastUserCPUTimes[p.Pid]; ok && timeDiff > 0 && total >= last {
+				usage.CPUPercent += (total - last) / timeDiff * 100
+			}
+		}
+
+		if memInfo, err := p.MemoryInfo(); err == nil {
+			usage.MemoryRSS += memInfo.RSS
+		}
+	}
+
+	// Update last values
+	lastUserCPUTimes = cpuTimes
+	lastUserSampleTime = now
+
+	result := make([]UserUsage, 0, len(byUID))
+	for _, usage := range byUID {
+		result = append(result, *usage)
+	}
+
+	sort.Slice(result, func(a, b int) bool {
+		if result[a].CPUPercent == result[b].CPUPercent {
+			return result[a].MemoryRSS > result[b].MemoryRSS
+		}
+		return result[a].CPUPercent > result[b].CPUPercent
+	})
+
+	return result, nil
+}
+
+// lookupUsername returns the login name for a UID, falling back to the number.
+// Results are cached since the user database rarely changes.
+func lookupUsername(uid uint32) string {
+	if name, ok := usernames[uid]; ok {
+		return name
+	}
+
+	name := strconv.FormatUint(uint64(uid), 10)
+	if u, err := user.LookupId(name); err == nil {
+		name = u.Username
+	}
+
+	usernames[uid] = name
+	return name
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// vmCounters holds the cumulative counters from /proc/vmstat
+type vmCounters struct {
+	swapIn     uint64 // Pages
+	swapOut    uint64 // Pages
+	majorFault uint64
+	oomKill    uint64
+}
+
+var (
+	lastVMCounters   vmCounters
+	lastVMSampleTime time.Time
+	firstOOMKills    uint64
+	vmMutex          sync.Mutex
+)
+
+// VMActivity contains virtual memory activity. Swap activity rather than swap usage
+// is what makes a desktop unresponsive.
+type VMActivity struct {
+	SwapInSpeed      float64 // Bytes per second read back from swap
+	SwapOutSpeed     float64 // Bytes per second written to swap
+	MajorFaultRate   float64 // Page faults per second that needed disk I/O
+	OOMKills         uint64  // Processes killed by the OOM killer since boot
+	OOMKillsObserved uint64  // Processes killed by the OOM killer since monitoring started
+}
+
+// GetVMActivity returns swap, page fault and OOM killer activity from /proc/vmstat.
+// Rates are computed from the change since the previous call, so the first call reports 0.
+func GetVMActivity() (VMActivity, error) {
+	vmMutex.Lock()
+	defer vmMutex.Unlock()
+
+	file, err := os.Open("/proc/vmstat")
+	if err != nil {
+		return VMActivity{}, err
+	}
+	defer file.Close()
+
+	var counters vmCounters
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		switch fields[0] {
+		case "pswpin":
+			counters.swapIn = value
+		case "pswpout":
+			counters.swapOut = value
+		case "pgmajfault":
+			counters.majorFault = value
+		case "oom_kill":
+			counters.oomKill = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return VMActivity{}, err
+	}
+
+	now := time.Now()
+	timeDiff := now.Sub(lastVMSampleTime).Seconds()
+	pageSize := float64(os.Getpagesize())
+
+	activity := VMActivity{OOMKills: counters.oomKill}
+	if lastVMSampleTime.IsZero() {
+		firstOOMKills = counters.oomKill
+	} else {
+		activity.SwapInSpeed = counterRate(counters.swapIn, lastVMCounters.swapIn, timeDiff) * pageSize
+		activity.SwapOutSpeed = counterRate(counters.swapOut, lastVMCounters.swapOut, timeDiff) * pageSize
+		activity.MajorFaultRate = counterRate(counters.majorFault, lastVMCounters.majorFault, timeDiff)
+	}
+	if counters.oomKill >= firstOOMKills {
+		activity.OOMKillsObserved = counters.oomKill - firstOOMKills
+	}
+
+	// Update last values
+	lastVMCounters = counters
+	lastVMSampleTime = now
+
+	return activity, nil
+}
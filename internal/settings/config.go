@@ -8,20 +8,122 @@ import (
 
 // Config represents the application settings
 type Config struct {
-	ShowCPU               bool     `json:"showCPU"`
-	ShowMemory            bool     `json:"showMemory"`
-	ShowNetwork           bool     `json:"showNetwork"`
-	ShowDisk              bool     `json:"showDisk"`
-	ShowCPUInTitle        bool     `json:"showCPUInTitle"`
-	ShowMemoryInTitle     bool     `json:"showMemoryInTitle"`
-	ShowNetworkInTitle    bool     `json:"showNetworkInTitle"`
-	ShowBothNetworkSpeeds bool     `json:"showBothNetworkSpeeds"` // Option for showing both upload and download
-	ShowDiskInTitle       bool     `json:"showDiskInTitle"`
-	RefreshInterval       int      `json:"refreshInterval"`
-	ShowMetrics           []string `json:"showMetrics"` // For compatibility with UI
+	ShowCPU               bool            `json:"showCPU"`
+	ShowMemory            bool            `json:"showMemory"`
+	ShowNetwork           bool            `json:"showNetwork"`
+	ShowDisk              bool            `json:"showDisk"`
+	ShowCPUInTitle        bool            `json:"showCPUInTitle"`
+	ShowMemoryInTitle     bool            `json:"showMemoryInTitle"`
+	ShowNetworkInTitle    bool            `json:"showNetworkInTitle"`
+	ShowBothNetworkSpeeds bool            `json:"showBothNetworkSpeeds"` // Option for showing both upload and download
+	ShowDiskInTitle       bool            `json:"showDiskInTitle"`
+	ShowProcesses         bool            `json:"showProcesses"`
+	TopProcessCount       int             `json:"topProcessCount"` // Number of processes listed in the menu
+	GroupProcessesByApp   bool            `json:"groupProcessesByApp"`
+	ShowDiskIO            bool            `json:"showDiskIO"`
+	ShowUsers             bool            `json:"showUsers"`
+	ShowServices          bool            `json:"showServices"`
+	PinnedServices        []string        `json:"pinnedServices"` // systemd services always shown in the menu
+	CgroupRoot            string          `json:"cgroupRoot"`     // Mount point of the cgroup v2 hierarchy
+	ShowContainers        bool            `json:"showContainers"`
+	ContainerSocket       string          `json:"containerSocket"`   // Docker-compatible API socket
+	ContainerAware        bool            `json:"containerAware"`    // Report usage against cgroup limits when in a container
+	ImagePruneCommand     []string        `json:"imagePruneCommand"` // Removes dangling images from the cleanup advisor
+	ShowSystemInfo        bool            `json:"showSystemInfo"`
+	ShowKernel            bool            `json:"showKernel"`
+	ShowFileHandles       bool            `json:"showFileHandles"`
+	FileHandleWarnPercent int             `json:"fileHandleWarnPercent"` // Warn in the title above this usage of a limit
+	ShowSwapActivity      bool            `json:"showSwapActivity"`
+	ShowSwapInTitle       bool            `json:"showSwapInTitle"`
+	ShowStorageHealth     bool            `json:"showStorageHealth"` // Also warns in the title when something is wrong
+	ShowDriveHealth       bool            `json:"showDriveHealth"`
+	SmartctlPath          string          `json:"smartctlPath"`
+	SmartDevices          []string        `json:"smartDevices"`     // Devices checked with smartctl, e.g. "/dev/nvme0"
+	SmartInterval         int             `json:"smartIntervalMin"` // Minutes between smartctl runs
+	ShowPower             bool            `json:"showPower"`
+	ShowPowerInTitle      bool            `json:"showPowerInTitle"`
+	DetectThrottling      bool            `json:"detectThrottling"` // Flag thermal throttling in the CPU item and title
+	ShowMaintenance       bool            `json:"showMaintenance"`  // Also shows a title badge when action is needed
+	SystemctlPath         string          `json:"systemctlPath"`
+	ShowRemovableDrives   bool            `json:"showRemovableDrives"`      // USB drives and SD cards in the disk submenu
+	WatchedDirectories    []string        `json:"watchedDirectories"`       // Directories whose size is shown in the menu
+	DirectoryScanInterval int             `json:"directoryScanIntervalMin"` // Minutes between directory scans
+	ProcessWatches        []ProcessWatch  `json:"processWatches"`
+	CommandMetrics        []CommandMetric `json:"commandMetrics"`
+	HTTPMetrics           []HTTPMetric    `json:"httpMetrics"`
+	Plugins               []Plugin        `json:"plugins"`
+	RefreshInterval       int             `json:"refreshInterval"`
+	ShowMetrics           []string        `json:"showMetrics"` // For compatibility with UI
 	configPath            string
 }
 
+// ProcessWatch describes a named group of processes whose combined usage is shown as a metric.
+// Processes are matched by exactly one of Name, Cmdline (a regular expression) or Pidfile.
+type ProcessWatch struct {
+	Label       string `json:"label"`
+	Name        string `json:"name,omitempty"`
+	Cmdline     string `json:"cmdline,omitempty"`
+	Pidfile     string `json:"pidfile,omitempty"`
+	ShowInTitle bool   `json:"showInTitle"`
+}
+
+// DisplayLabel returns the name used for the watch in the menu and title
+func (w ProcessWatch) DisplayLabel() string {
+	if w.Label != "" {
+		return w.Label
+	}
+	if w.Name != "" {
+		return w.Name
+	}
+	if w.Pidfile != "" {
+		return filepath.Base(w.Pidfile)
+	}
+	return w.Cmdline
+}
+
+// CommandMetric describes a metric read from the output of a shell command.
+// The number is extracted with Pattern, a regular expression whose first group
+// is the number and optional second group the unit, or with JSONPath when the
+// command prints JSON. Without either, the output should start with the number.
+type CommandMetric struct {
+	Label       string `json:"label"`
+	Command     string `json:"command"`                  // Run with sh -c
+	Interval    int    `json:"intervalSec,omitempty"`    // Seconds between runs, 30 if unset
+	Timeout     int    `json:"timeoutSec,omitempty"`     // Seconds before the command is killed, 10 if unset
+	MaxOutput   int    `json:"maxOutputBytes,omitempty"` // Output beyond this is ignored, 64 KiB if unset
+	Pattern     string `json:"pattern,omitempty"`
+	JSONPath    string `json:"jsonPath,omitempty"` // e.g. ".queue.pending"
+	Unit        string `json:"unit,omitempty"`
+	ShowInTitle bool   `json:"showInTitle"`
+}
+
+// HTTPMetric describes a metric read from a JSON status endpoint.
+// Values past the thresholds are flagged in the menu and title.
+type HTTPMetric struct {
+	Label         string            `json:"label"`
+	URL           string            `json:"url"`
+	JSONPath      string            `json:"jsonPath"` // e.g. ".queue.pending"
+	Unit          string            `json:"unit,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Token         string            `json:"token,omitempty"`       // Sent as a bearer token
+	TokenFile     string            `json:"tokenFile,omitempty"`   // Keeps the token out of the config file
+	Interval      int               `json:"intervalSec,omitempty"` // Seconds between requests, 30 if unset
+	Timeout       int               `json:"timeoutSec,omitempty"`  // Seconds before a request is abandoned, 5 if unset
+	WarnAbove     *float64          `json:"warnAbove,omitempty"`
+	CriticalAbove *float64          `json:"criticalAbove,omitempty"`
+	WarnBelow     *float64          `json:"warnBelow,omitempty"`
+	CriticalBelow *float64          `json:"criticalBelow,omitempty"`
+	ShowInTitle   bool              `json:"showInTitle"`
+}
+
+// Plugin describes a long-running executable that reports metrics over a JSON-lines protocol
+type Plugin struct {
+	Label       string   `json:"label"`
+	Command     string   `json:"command"`
+	Args        []string `json:"args,omitempty"`
+	ShowInTitle []string `json:"showInTitle,omitempty"` // Names of the plugin's metrics shown in the title
+}
+
 // DefaultSettings returns the default application settings
 func DefaultSettings() *Config {
 	homeDir, _ := os.UserHomeDir()
@@ -37,6 +139,41 @@ func DefaultSettings() *Config {
 		ShowNetworkInTitle:    false,
 		ShowBothNetworkSpeeds: false, // Off by default to save space
 		ShowDiskInTitle:       false,
+		ShowProcesses:         true,
+		TopProcessCount:       5,
+		GroupProcessesByApp:   false,
+		ShowDiskIO:            true,
+		ShowUsers:             false, // Mostly useful on shared machines
+		ShowServices:          false,
+		PinnedServices:        []string{},
+		CgroupRoot:            "/sys/fs/cgroup",
+		ShowContainers:        false,
+		ContainerSocket:       "/var/run/docker.sock",
+		ContainerAware:        true,
+		ImagePruneCommand:     []string{"docker", "image", "prune", "--force"},
+		ShowSystemInfo:        true,
+		ShowKernel:            false,
+		ShowFileHandles:       false,
+		FileHandleWarnPercent: 90,
+		ShowSwapActivity:      true,
+		ShowSwapInTitle:       false,
+		ShowStorageHealth:     true,
+		ShowDriveHealth:       false,
+		SmartctlPath:          "smartctl",
+		SmartDevices:          []string{},
+		SmartInterval:         60,
+		ShowPower:             false, // RAPL counters are only readable by root by default
+		ShowPowerInTitle:      false,
+		DetectThrottling:      true,
+		ShowMaintenance:       true,
+		SystemctlPath:         "systemctl",
+		ShowRemovableDrives:   true,
+		WatchedDirectories:    []string{},
+		DirectoryScanInterval: 30,
+		ProcessWatches:        []ProcessWatch{},
+		CommandMetrics:        []CommandMetric{},
+		HTTPMetrics:           []HTTPMetric{},
+		Plugins:               []Plugin{},
 		RefreshInterval:       2,
 		ShowMetrics:           []string{"cpu", "memory", "network", "disk"},
 		configPath:            filepath.Join(configDir, "config.json"),
@@ -0,0 +1,238 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/casper9429-kth/task_bar_monitor/internal/metrics"
+	"github.com/getlantern/systray"
+)
+
+const (
+	// maxMountPoints is the number of mount points offered for analysis
+	maxMountPoints = 10
+
+	// maxDiskResults is the number of directories and files listed after an analysis
+	maxDiskResults = 10
+
+	// analyzerProgressInterval is how often the progress of an analysis is shown
+	analyzerProgressInterval = 500 * time.Millisecond
+)
+
+// analyzerMenu holds the disk usage analysis items of the disk submenu
+type analyzerMenu struct {
+	analyzeItem *systray.MenuItem
+	mountSlots  []*systray.MenuItem
+	mounts      []string // Mount points shown in mountSlots
+	latest      []string // Mount points to show once no analysis is running
+	cancelItem  *systray.MenuItem
+	dirsItem    *systray.MenuItem
+	dirSlots    []*systray.MenuItem
+	filesItem   *systray.MenuItem
+	fileSlots   []*systray.MenuItem
+	analyzer    *metrics.DiskAnalyzer
+	mutex       sync.Mutex
+}
+
+// newAnalyzerMenu adds the disk usage analysis items to parent
+func newAnalyzerMenu(parent *systray.MenuItem) *analyzerMenu {
+	m := &analyzerMenu{
+		analyzeItem: parent.AddSubMenuItem("Analyze disk usage…", "Find the largest directories and files on a filesystem"),
+	}
+	for n := 0; n < maxMountPoints; n++ {
+		slot := m.analyzeItem.AddSubMenuItem("", "Analyze this filesystem")
+		slot.Hide()
+		m.mountSlots = append(m.mountSlots, slot)
+	}
+	m.cancelItem = parent.AddSubMenuItem("Cancel analysis", "Stop the disk usage analysis")
+	m.cancelItem.Hide()
+
+	m.dirsItem = parent.AddSubMenuItem("Largest directories", "Directories using the most space")
+	for n := 0; n < maxDiskResults; n++ {
+		slot := m.dirsItem.AddSubMenuItem("", "Directory size")
+		slot.Hide()
+		m.dirSlots = append(m.dirSlots, slot)
+	}
+	m.dirsItem.Hide()
+
+	m.filesItem = parent.AddSubMenuItem("Largest files", "Files using the most space")
+	for n := 0; n < maxDiskResults; n++ {
+		slot := m.filesItem.AddSubMenuItem("", "File size")
+		slot.Hide()
+		m.fileSlots = append(m.fileSlots, slot)
+	}
+	m.filesItem.Hide()
+
+	// Until the mount watcher has read the mount table
+	mounts, err := metrics.GetMountPoints()
+	if err != nil {
+		log.Printf("Failed to list mount points: %v", err)
+	}
+	m.setMounts(mounts)
+	return m
+}
+
+// setMounts offers mounts for analysis. While an analysis is running the slots
+// keep their mount points, and the new ones are shown when it finishes.
+func (m *analyzerMenu) setMounts(mounts []string) {
+	if mounts == nil {
+		return
+	}
+	if len(mounts) > maxMountPoints {
+		mounts = mounts[:maxMountPoints]
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.latest = mounts
+	if m.analyzer == nil {
+		m.showMounts()
+	}
+}
+
+// showMounts fills the slots with the latest mount points, if they changed.
+// The caller holds m.mutex.
+func (m *analyzerMenu) showMounts() {
+	if sameStrings(m.latest, m.mounts) {
+		return
+	}
+	mounts := m.latest
+	m.mounts = mounts
+
+	for n, slot := range m.mountSlots {
+		if n < len(mounts) {
+			slot.SetTitle(mounts[n])
+			slot.Show()
+		} else {
+			slot.Hide()
+		}
+	}
+}
+
+// sameStrings reports whether a and b hold the same strings in the same order
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for n := range a {
+		if a[n] != b[n] {
+			return false
+		}
+	}
+	return true
+}
+
+// handleEvents starts and cancels analyses as their items are clicked
+func (m *analyzerMenu) handleEvents(stopChan chan struct{}) {
+	for n, slot := range m.mountSlots {
+		go func(n int, slot *systray.MenuItem) {
+			for {
+				select {
+				case <-slot.ClickedCh:
+					m.start(n)
+				case <-stopChan:
+					return
+				}
+			}
+		}(n, slot)
+	}
+
+	for {
+		select {
+		case <-m.cancelItem.ClickedCh:
+			m.mutex.Lock()
+			if m.analyzer != nil {
+				m.analyzer.Cancel()
+			}
+			m.mutex.Unlock()
+		case <-stopChan:
+			m.mutex.Lock()
+			if m.analyzer != nil {
+				m.analyzer.Cancel()
+			}
+			m.mutex.Unlock()
+			return
+		}
+	}
+}
+
+// start analyzes the mount point shown in slot n, unless an analysis is already running
+func (m *analyzerMenu) start(n int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.analyzer != nil || n >= len(m.mounts) {
+		return
+	}
+
+	root := m.mounts[n]
+	log.Printf("Analyzing disk usage of %s", root)
+	m.analyzer = metrics.StartDiskAnalysis(root, maxDiskResults)
+	m.analyzeItem.SetTitle(fmt.Sprintf("Analyzing %s…", root))
+	m.cancelItem.Show()
+
+	go m.track(m.analyzer)
+}
+
+// track shows the progress of an analysis and then its results
+func (m *analyzerMenu) track(a *metrics.DiskAnalyzer) {
+	ticker := time.NewTicker(analyzerProgressInterval)
+	defer ticker.Stop()
+
+	for done := false; !done; {
+		select {
+		case <-ticker.C:
+			files, bytes := a.Progress()
+			m.analyzeItem.SetTitle(fmt.Sprintf("Analyzing %s… %d files, %s",
+				a.Root(), files, metrics.FormatBytes(bytes)))
+		case <-a.Done():
+			done = true
+		}
+	}
+
+	report, err := a.Result()
+
+	m.mutex.Lock()
+	m.analyzer = nil
+	m.showMounts()
+	m.mutex.Unlock()
+
+	m.cancelItem.Hide()
+	m.analyzeItem.SetTitle("Analyze disk usage…")
+
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			log.Printf("Disk usage analysis of %s failed: %v", a.Root(), err)
+			m.analyzeItem.SetTitle(fmt.Sprintf("Analyze disk usage… (⚠ %v)", err))
+		}
+		return
+	}
+
+	log.Printf("Analyzed %s: %s in %d files in %s", report.Root, metrics.FormatBytes(report.Size),
+		report.Files, report.Elapsed.Round(time.Second))
+
+	m.dirsItem.SetTitle(fmt.Sprintf("Largest directories in %s (%s)", report.Root, metrics.FormatBytes(report.Size)))
+	showDiskEntries(m.dirSlots, report.Directories)
+	m.dirsItem.Show()
+
+	m.filesItem.SetTitle(fmt.Sprintf("Largest files in %s", report.Root))
+	showDiskEntries(m.fileSlots, report.LargeFiles)
+	m.filesItem.Show()
+}
+
+// showDiskEntries fills slots with the entries of an analysis
+func showDiskEntries(slots []*systray.MenuItem, entries []metrics.DiskEntry) {
+	for n, slot := range slots {
+		if n < len(entries) {
+			slot.SetTitle(fmt.Sprintf("%s: %s", shortenHome(entries[n].Path), metrics.FormatBytes(entries[n].Size)))
+			slot.Show()
+		} else {
+			slot.Hide()
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/casper9429-kth/task_bar_monitor/internal/metrics"
+	"github.com/casper9429-kth/task_bar_monitor/internal/settings"
+	"github.com/getlantern/systray"
+)
+
+// maxCleanupItems is the number of reclaimable locations listed in the menu
+const maxCleanupItems = 10
+
+// cleanupSlot is a menu entry for a reclaimable location and its clean action
+type cleanupSlot struct {
+	item       *systray.MenuItem
+	actionItem *systray.MenuItem
+	expires    time.Time // When a pending clean stops waiting for its confirming click
+}
+
+// cleanupMenu is the disk submenu listing reclaimable space
+type cleanupMenu struct {
+	item        *systray.MenuItem
+	measureItem *systray.MenuItem
+	slots       []*cleanupSlot
+	locations   []metrics.ReclaimableSpace
+	busy        bool // Measuring or cleaning
+	settings    *settings.Config
+	mutex       sync.Mutex
+}
+
+// newCleanupMenu adds the reclaimable space submenu to parent
+func newCleanupMenu(parent *systray.MenuItem, s *settings.Config) *cleanupMenu {
+	m := &cleanupMenu{
+		item:     parent.AddSubMenuItem("Reclaimable space", "Caches and other locations that can be cleaned"),
+		settings: s,
+	}
+	m.measureItem = m.item.AddSubMenuItem("Measure reclaimable space", "Measure caches, Trash and old packages")
+	for n := 0; n < maxCleanupItems; n++ {
+		slot := &cleanupSlot{item: m.item.AddSubMenuItem("", "Reclaimable space")}
+		slot.actionItem = slot.item.AddSubMenuItem("", "Delete the contents of this location")
+		slot.item.Hide()
+		m.slots = append(m.slots, slot)
+	}
+	return m
+}
+
+// handleEvents measures and cleans locations as their items are clicked
+func (m *cleanupMenu) handleEvents(stopChan chan struct{}) {
+	for n, slot := range m.slots {
+		go func(n int, slot *cleanupSlot) {
+			for {
+				select {
+				case <-slot.actionItem.ClickedCh:
+					m.handleClean(n)
+				case <-stopChan:
+					return
+				}
+			}
+		}(n, slot)
+	}
+
+	for {
+		select {
+		case <-m.measureItem.ClickedCh:
+			go m.measure()
+		case <-stopChan:
+			return
+		}
+	}
+}
+
+// measure finds reclaimable space in the background and lists it
+func (m *cleanupMenu) measure() {
+	m.mutex.Lock()
+	if m.busy {
+		m.mutex.Unlock()
+		return
+	}
+	m.busy = true
+	m.mutex.Unlock()
+
+	m.measureItem.SetTitle("Measuring…")
+	m.measureItem.Disable()
+
+	locations := metrics.FindReclaimableSpace(context.Background(), metrics.CleanupOptions{
+		ContainerSocket:   m.settings.ContainerSocket,
+		ImagePruneCommand: m.settings.ImagePruneCommand,
+	})
+	if len(locations) > maxCleanupItems {
+		locations = locations[:maxCleanupItems]
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.locations = locations
+	m.busy = false
+	m.item.SetTitle(fmt.Sprintf("Reclaimable space: %s", metrics.FormatBytes(metrics.ReclaimableTotal(locations))))
+	m.measureItem.SetTitle(fmt.Sprintf("Measure again (last measured %s)", time.Now().Format("15:04")))
+	m.measureItem.Enable()
+
+	for n, slot := range m.slots {
+		slot.expires = time.Time{}
+		if n >= len(locations) {
+			slot.item.Hide()
+			continue
+		}
+
+		l := locations[n]
+		slot.item.SetTitle(fmt.Sprintf("%s: %s", l.Name, metrics.FormatBytes(l.Size)))
+		if l.Path != "" {
+			slot.item.SetTooltip(shortenHome(l.Path))
+		}
+		m.resetAction(slot, l)
+		slot.item.Show()
+	}
+}
+
+// resetAction shows how a location can be cleaned, or why it can't be from here
+func (m *cleanupMenu) resetAction(slot *cleanupSlot, l metrics.ReclaimableSpace) {
+	switch {
+	case l.Error != "":
+		slot.actionItem.SetTitle("⚠ " + l.Error)
+		slot.actionItem.Disable()
+	case l.Cleanable():
+		slot.actionItem.SetTitle("Clean…")
+		slot.actionItem.Enable()
+	case l.Hint != "":
+		slot.actionItem.SetTitle("Needs root: " + l.Hint)
+		slot.actionItem.Disable()
+	default:
+		slot.actionItem.SetTitle("Cannot be cleaned from here")
+		slot.actionItem.Disable()
+	}
+}
+
+// handleClean asks for confirmation on the first click and cleans on the second
+func (m *cleanupMenu) handleClean(n int) {
+	m.mutex.Lock()
+	if m.busy || n >= len(m.locations) {
+		m.mutex.Unlock()
+		return
+	}
+
+	slot, l := m.slots[n], m.locations[n]
+	if !l.Cleanable() {
+		m.mutex.Unlock()
+		return
+	}
+
+	// Deleting a cache cannot be undone, so ask for a second click first
+	if time.Now().After(slot.expires) {
+		slot.expires = time.Now().Add(confirmTimeout)
+		slot.actionItem.SetTitle(fmt.Sprintf("Click again to delete %s from %s", metrics.FormatBytes(l.Size), l.Name))
+		m.mutex.Unlock()
+
+		time.AfterFunc(confirmTimeout, func() {
+			m.mutex.Lock()
+			defer m.mutex.Unlock()
+			if !slot.expires.IsZero() && time.Now().After(slot.expires) && !m.busy {
+				slot.expires = time.Time{}
+				m.resetAction(slot, l)
+			}
+		})
+		return
+	}
+
+	slot.expires = time.Time{}
+	m.busy = true
+	m.mutex.Unlock()
+
+	slot.actionItem.SetTitle("Cleaning…")
+	slot.actionItem.Disable()
+	log.Printf("Cleaning %s (%s)", l.Name, l.Path)
+
+	if err := l.Clean(); err != nil {
+		log.Printf("Failed to clean %s: %v", l.Name, err)
+		slot.actionItem.SetTitle(fmt.Sprintf("⚠ %v", err))
+		m.mutex.Lock()
+		m.busy = false
+		m.mutex.Unlock()
+		return
+	}
+
+	// Measure again so the sizes reflect what was freed
+	m.mutex.Lock()
+	m.busy = false
+	m.mutex.Unlock()
+	m.measure()
+}
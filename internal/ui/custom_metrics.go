@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/casper9429-kth/task_bar_monitor/internal/metrics"
+)
+
+// formatCustomTitle returns the short taskbar form of a user-defined metric, e.g. "jobs: 42"
+// or "⚠ jobs: 250" past a warning threshold
+func formatCustomTitle(v metrics.CustomMetricValue) string {
+	if v.Updated.IsZero() {
+		if v.Error != "" {
+			return v.Label + ": ⚠"
+		}
+		return v.Label + ": …"
+	}
+
+	text := fmt.Sprintf("%s%s: %s", levelPrefix(v.Level), v.Label, formatCustomValue(v.Value, v.Unit))
+	if v.Error != "" {
+		// The value is stale, but still more useful than nothing
+		text += " ⚠"
+	}
+	return text
+}
+
+// formatCustomDetails returns the menu form of a user-defined metric, including why the latest reading failed
+func formatCustomDetails(v metrics.CustomMetricValue) string {
+	switch {
+	case v.Updated.IsZero() && v.Error != "":
+		return fmt.Sprintf("%s: ⚠ %s", v.Label, v.Error)
+	case v.Updated.IsZero():
+		return v.Label + ": Loading..."
+	case v.Error != "":
+		return fmt.Sprintf("%s%s: %s (⚠ %s, last read at %s)", levelPrefix(v.Level), v.Label,
+			formatCustomValue(v.Value, v.Unit), v.Error, v.Updated.Format("15:04"))
+	}
+	return fmt.Sprintf("%s%s: %s", levelPrefix(v.Level), v.Label, formatCustomValue(v.Value, v.Unit))
+}
+
+// levelPrefix marks a value past its warning or critical threshold
+func levelPrefix(level metrics.AlertLevel) string {
+	switch level {
+	case metrics.LevelCritical:
+		return "‼ "
+	case metrics.LevelWarning:
+		return "⚠ "
+	}
+	return ""
+}
+
+// formatCustomValue shows whole numbers as they are and others with two decimals, followed by the unit
+func formatCustomValue(value float64, unit string) string {
+	text := strconv.FormatFloat(value, 'f', 2, 64)
+	if value == math.Trunc(value) && math.Abs(value) < 1e15 {
+		text = strconv.FormatFloat(value, 'f', 0, 64)
+	}
+
+	switch {
+	case unit == "":
+		return text
+	case unit == "%":
+		return text + unit
+	}
+	return text + " " + unit
+}
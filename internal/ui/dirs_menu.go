@@ -0,0 +1,77 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/casper9429-kth/task_bar_monitor/internal/metrics"
+	"github.com/getlantern/systray"
+)
+
+// dirsMenu is the submenu showing the size of watched directories
+type dirsMenu struct {
+	item  *systray.MenuItem
+	slots []*systray.MenuItem
+}
+
+// newDirsMenu creates the watched directories submenu with one entry per configured path
+func newDirsMenu(paths []string) *dirsMenu {
+	m := &dirsMenu{
+		item: systray.AddMenuItem("Watched directories", "Size of directories listed in the config file"),
+	}
+	for _, p := range paths {
+		m.slots = append(m.slots, m.item.AddSubMenuItem(shortenHome(metrics.ExpandHome(p))+": Scanning...", "Directory size"))
+	}
+	return m
+}
+
+// update shows the latest directory sizes, in the order they were configured
+func (m *dirsMenu) update(dirs []metrics.DirectorySize) {
+	for n, slot := range m.slots {
+		if n >= len(dirs) {
+			break
+		}
+
+		d := dirs[n]
+		name := shortenHome(d.Path)
+		switch {
+		case d.Error != "":
+			slot.SetTitle(fmt.Sprintf("⚠ %s: %s", name, d.Error))
+			continue
+		case d.Scanned.IsZero():
+			slot.SetTitle(name + ": Scanning...")
+			continue
+		}
+
+		text := fmt.Sprintf("%s: %s", name, metrics.FormatBytes(d.Size))
+		if d.Growth != 0 {
+			text += fmt.Sprintf(", %s since last scan", formatGrowth(d.Growth))
+		}
+		if d.Scanning {
+			text += " (rescanning)"
+		}
+		slot.SetTitle(text)
+		slot.SetTooltip(fmt.Sprintf("%d files, scanned at %s", d.Files, d.Scanned.Format("15:04")))
+	}
+}
+
+// formatGrowth returns a signed size change, e.g. "+1.2 GB" or "-300.0 MB"
+func formatGrowth(delta int64) string {
+	if delta < 0 {
+		return "-" + metrics.FormatBytes(uint64(-delta))
+	}
+	return "+" + metrics.FormatBytes(uint64(delta))
+}
+
+// shortenHome replaces the user's home directory at the start of a path with "~"
+func shortenHome(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" || home == "/" {
+		return path
+	}
+	if path == home || strings.HasPrefix(path, home+"/") {
+		return "~" + path[len(home):]
+	}
+	return path
+}
@@ -0,0 +1,92 @@
+package ui
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/casper9429-kth/task_bar_monitor/internal/metrics"
+	"github.com/getlantern/systray"
+)
+
+// maxDrives is the number of drives listed in the disk health submenu
+const maxDrives = 10
+
+// driveMenu is the submenu showing SMART health of the configured drives
+type driveMenu struct {
+	item  *systray.MenuItem
+	slots []*systray.MenuItem
+}
+
+// newDriveMenu creates the disk health submenu
+func newDriveMenu() *driveMenu {
+	m := &driveMenu{
+		item: systray.AddMenuItem("Disk health: Checking...", "SMART health reported by smartctl"),
+	}
+	for n := 0; n < maxDrives; n++ {
+		slot := m.item.AddSubMenuItem("", "Drive health")
+		slot.Hide()
+		m.slots = append(m.slots, slot)
+	}
+	return m
+}
+
+// update shows the latest smartctl results, one drive per line
+func (m *driveMenu) update(drives []metrics.DriveHealth) {
+	unhealthy := 0
+	for _, d := range drives {
+		if !d.Healthy() {
+			unhealthy++
+		}
+	}
+
+	switch {
+	case len(drives) == 0:
+		m.item.SetTitle("Disk health: Checking...")
+	case unhealthy > 0:
+		m.item.SetTitle(fmt.Sprintf("Disk health: ⚠ %d of %d %s need attention",
+			unhealthy, len(drives), plural(len(drives), "drive", "drives")))
+	default:
+		m.item.SetTitle("Disk health: OK")
+	}
+
+	for n, slot := range m.slots {
+		if n < len(drives) {
+			slot.SetTitle(formatDriveHealth(drives[n]))
+			slot.SetTooltip(fmt.Sprintf("Checked at %s", drives[n].Checked.Format("15:04")))
+			slot.Show()
+		} else {
+			slot.Hide()
+		}
+	}
+}
+
+// formatDriveHealth describes a drive, e.g. "nvme0 (Samsung 980): PASSED, 41°C, 3% used"
+func formatDriveHealth(d metrics.DriveHealth) string {
+	name := path.Base(d.Device)
+	if d.Error != "" {
+		return fmt.Sprintf("%s: ⚠ %s", name, d.Error)
+	}
+
+	text := name
+	if d.Model != "" {
+		text += fmt.Sprintf(" (%s)", d.Model)
+	}
+	if d.Passed {
+		text += ": PASSED"
+	} else {
+		text += ": ⚠ FAILING"
+	}
+	if d.Temperature > 0 {
+		text += fmt.Sprintf(", %d°C", d.Temperature)
+	}
+	if d.PercentageUsed >= 0 {
+		text += fmt.Sprintf(", %d%% used", d.PercentageUsed)
+	}
+	if d.ReallocatedSectors > 0 {
+		text += fmt.Sprintf(", ⚠ %d reallocated sectors", d.ReallocatedSectors)
+	} else if d.ReallocatedSectors == 0 {
+		text += ", no reallocated sectors"
+	}
+
+	return text
+}
@@ -11,6 +11,27 @@ import (
 type TrayInterface interface {
 	Start()
 	UpdateMetrics(cpuUsage, memUsage, diskUsage float64, netUsage metrics.NetworkUsage)
+	UpdateProcesses(procs []metrics.ProcessInfo)
+	UpdateAppGroups(groups []metrics.AppGroup)
+	UpdateDiskIO(procs []metrics.ProcessIO)
+	UpdateUsers(users []metrics.UserUsage)
+	UpdateServices(services []metrics.ServiceUsage)
+	UpdateContainers(containers []metrics.ContainerStats)
+	UpdateKernelActivity(activity metrics.KernelActivity)
+	UpdateFileHandles(usage metrics.FileHandleUsage)
+	UpdateVMActivity(activity metrics.VMActivity)
+	UpdateStorageHealth(health metrics.StorageHealth)
+	UpdateDriveHealth(drives []metrics.DriveHealth)
+	UpdatePower(usage metrics.PowerUsage, err error)
+	UpdateThrottleStatus(status metrics.ThrottleStatus)
+	UpdateMaintenance(status metrics.MaintenanceStatus)
+	UpdateDirectories(dirs []metrics.DirectorySize)
+	UpdateRemovableDrives(drives []metrics.RemovableDrive)
+	UpdateMountPoints(mounts []string)
+	UpdateSystemInfo(info metrics.SystemInfo)
+	UpdateWatchedProcesses(stats []metrics.WatchedProcessStats)
+	UpdateCustomMetrics(commands, endpoints []metrics.CustomMetricValue)
+	UpdatePlugins(statuses []metrics.PluginStatus)
 	Stop()
 }
 
@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/casper9429-kth/task_bar_monitor/internal/metrics"
+	"github.com/getlantern/systray"
+)
+
+// filesMenu is the submenu showing file handle and inotify usage
+type filesMenu struct {
+	item        *systray.MenuItem
+	openItem    *systray.MenuItem
+	inotifyItem *systray.MenuItem
+	topItem     *systray.MenuItem
+	topSlots    []*systray.MenuItem
+}
+
+// newFilesMenu creates the file handle submenu
+func newFilesMenu() *filesMenu {
+	m := &filesMenu{
+		item: systray.AddMenuItem("Files: Loading...", "Open files and inotify watches"),
+	}
+	m.openItem = m.item.AddSubMenuItem("Open files: Loading...", "System-wide file handles")
+	m.inotifyItem = m.item.AddSubMenuItem("inotify watches: Loading...", "inotify watches held by your processes")
+	m.topItem = m.item.AddSubMenuItem("Most open descriptors", "Processes with the most open file descriptors")
+	for n := 0; n < maxTopProcesses; n++ {
+		slot := m.topItem.AddSubMenuItem("", "Open file descriptors")
+		slot.Hide()
+		m.topSlots = append(m.topSlots, slot)
+	}
+	return m
+}
+
+// update shows the latest file handle usage, marking limits that are nearly reached
+func (m *filesMenu) update(u metrics.FileHandleUsage, warnPercent int) {
+	openWarn, inotifyWarn := "", ""
+	if u.OpenFilesPercent() >= float64(warnPercent) {
+		openWarn = "⚠ "
+	}
+	if u.InotifyPercent() >= float64(warnPercent) {
+		inotifyWarn = "⚠ "
+	}
+
+	m.item.SetTitle(fmt.Sprintf("Files: %s%.0f%% handles, %s%.0f%% inotify",
+		openWarn, u.OpenFilesPercent(), inotifyWarn, u.InotifyPercent()))
+	m.openItem.SetTitle(fmt.Sprintf("%sOpen files: %d of %d", openWarn, u.OpenFiles, u.MaxFiles))
+	m.inotifyItem.SetTitle(fmt.Sprintf("%sinotify watches: %d of %d (%d instances)",
+		inotifyWarn, u.InotifyWatches, u.MaxInotifyWatches, u.InotifyInstances))
+
+	for n, slot := range m.topSlots {
+		if n < len(u.TopProcesses) {
+			p := u.TopProcesses[n]
+			slot.SetTitle(fmt.Sprintf("%s (%d): %d FDs", p.Name, p.PID, p.FDs))
+			slot.Show()
+		} else {
+			slot.Hide()
+		}
+	}
+}
+
+// formatFileHandleWarning returns a short title warning for limits that are nearly reached, or ""
+func formatFileHandleWarning(u metrics.FileHandleUsage, warnPercent int) string {
+	switch {
+	case u.InotifyPercent() >= float64(warnPercent):
+		return fmt.Sprintf("⚠ inotify %.0f%%", u.InotifyPercent())
+	case u.OpenFilesPercent() >= float64(warnPercent):
+		return fmt.Sprintf("⚠ files %.0f%%", u.OpenFilesPercent())
+	default:
+		return ""
+	}
+}
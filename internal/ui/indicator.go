@@ -14,9 +14,47 @@ import (
 // Indicator represents the system tray indicator
 type Indicator struct {
 	cpuItem           *systray.MenuItem
+	powerMenu         *powerMenu
+	power             *metrics.PowerUsage
+	throttle          *metrics.ThrottleStatus
 	memoryItem        *systray.MenuItem
 	networkItem       *systray.MenuItem
 	diskItem          *systray.MenuItem
+	removableMenu     *removableMenu
+	analyzerMenu      *analyzerMenu
+	cleanupMenu       *cleanupMenu
+	processesItem     *systray.MenuItem
+	processSlots      []*processSlot
+	groupByAppItem    *systray.MenuItem
+	diskIOItem        *systray.MenuItem
+	diskIOSlots       []*systray.MenuItem
+	usersItem         *systray.MenuItem
+	userSlots         []*systray.MenuItem
+	servicesItem      *systray.MenuItem
+	serviceSlots      []*systray.MenuItem
+	pinnedItems       map[string]*systray.MenuItem
+	containersItem    *systray.MenuItem
+	containerSlots    []*systray.MenuItem
+	kernelMenu        *kernelMenu
+	filesMenu         *filesMenu
+	fileHandles       *metrics.FileHandleUsage
+	swapMenu          *swapMenu
+	vmActivity        *metrics.VMActivity
+	storageMenu       *storageMenu
+	storageHealth     *metrics.StorageHealth
+	driveMenu         *driveMenu
+	maintenanceMenu   *maintenanceMenu
+	maintenance       *metrics.MaintenanceStatus
+	infoMenu          *infoMenu
+	dirsMenu          *dirsMenu
+	watchItems        []*systray.MenuItem // One per configured watch, in config order
+	watchStats        []metrics.WatchedProcessStats
+	commandItems      []*systray.MenuItem // One per configured command metric, in config order
+	commandValues     []metrics.CustomMetricValue
+	httpItems         []*systray.MenuItem // One per configured HTTP metric, in config order
+	httpValues        []metrics.CustomMetricValue
+	pluginMenus       []*pluginMenu // One per configured plugin, in config order
+	pluginStatuses    []metrics.PluginStatus
 	settingsItem      *systray.MenuItem
 	quitItem          *systray.MenuItem
 	settings          *settings.Config
@@ -31,9 +69,10 @@ type Indicator struct {
 func NewIndicator(s *settings.Config) *Indicator {
 	log.Println("Creating new indicator")
 	return &Indicator{
-		settings: s,
-		ready:    false,
-		stopChan: make(chan struct{}),
+		settings:    s,
+		ready:       false,
+		stopChan:    make(chan struct{}),
+		pinnedItems: make(map[string]*systray.MenuItem),
 	}
 }
 
@@ -45,6 +84,7 @@ func NewIndicatorWithCallback(s *settings.Config, callback func()) *Indicator {
 		ready:             false,
 		stopChan:          make(chan struct{}),
 		onSettingsChanged: callback,
+		pinnedItems:       make(map[string]*systray.MenuItem),
 	}
 }
 
@@ -64,9 +104,71 @@ func (i *Indicator) onReady() {
 
 	// Create menu items
 	i.cpuItem = systray.AddMenuItem("CPU: Loading...", "CPU Usage")
+	i.powerMenu = newPowerMenu()
 	i.memoryItem = systray.AddMenuItem("Memory: Loading...", "Memory Usage")
 	i.networkItem = systray.AddMenuItem("Network: Loading...", "Network Usage")
 	i.diskItem = systray.AddMenuItem("Disk: Loading...", "Disk Usage")
+	i.removableMenu = newRemovableMenu(i.diskItem)
+	i.analyzerMenu = newAnalyzerMenu(i.diskItem)
+	i.cleanupMenu = newCleanupMenu(i.diskItem, i.settings)
+	i.processesItem = systray.AddMenuItem("Top Processes", "Processes using the most CPU")
+	i.groupByAppItem = i.processesItem.AddSubMenuItemCheckbox("Group by application",
+		"Combine processes launched by the same application", i.settings.GroupProcessesByApp)
+	for n := 0; n < maxTopProcesses; n++ {
+		i.processSlots = append(i.processSlots, newProcessSlot(i.processesItem))
+	}
+	i.diskIOItem = systray.AddMenuItem("Top Disk I/O", "Processes reading or writing the most data")
+	for n := 0; n < maxTopProcesses; n++ {
+		slot := i.diskIOItem.AddSubMenuItem("", "Disk throughput")
+		slot.Hide()
+		i.diskIOSlots = append(i.diskIOSlots, slot)
+	}
+	i.usersItem = systray.AddMenuItem("Users", "Resource usage by user")
+	for n := 0; n < maxTopProcesses; n++ {
+		slot := i.usersItem.AddSubMenuItem("", "User resource usage")
+		slot.Hide()
+		i.userSlots = append(i.userSlots, slot)
+	}
+	i.servicesItem = systray.AddMenuItem("Services", "systemd services using the most resources")
+	for n := 0; n < maxTopProcesses; n++ {
+		slot := i.servicesItem.AddSubMenuItem("", "Service resource usage")
+		slot.Hide()
+		i.serviceSlots = append(i.serviceSlots, slot)
+	}
+	i.containersItem = systray.AddMenuItem("Containers", "Running Docker or Podman containers")
+	for n := 0; n < maxContainers; n++ {
+		slot := i.containersItem.AddSubMenuItem("", "Container resource usage")
+		slot.Hide()
+		i.containerSlots = append(i.containerSlots, slot)
+	}
+	i.kernelMenu = newKernelMenu()
+	i.filesMenu = newFilesMenu()
+	i.swapMenu = newSwapMenu()
+	i.storageMenu = newStorageMenu()
+	i.driveMenu = newDriveMenu()
+	for _, unit := range i.settings.PinnedServices {
+		name := metrics.ServiceName(unit)
+		i.pinnedItems[name] = systray.AddMenuItem(name+": Loading...", "Pinned service")
+	}
+	for _, w := range i.settings.ProcessWatches {
+		i.watchItems = append(i.watchItems, systray.AddMenuItem(w.DisplayLabel()+": Loading...", "Watched process"))
+	}
+	for _, c := range i.settings.CommandMetrics {
+		i.commandItems = append(i.commandItems, systray.AddMenuItem(c.Label+": Loading...", c.Command))
+	}
+	for _, h := range i.settings.HTTPMetrics {
+		i.httpItems = append(i.httpItems, systray.AddMenuItem(h.Label+": Loading...", h.URL))
+	}
+	for _, p := range i.settings.Plugins {
+		i.pluginMenus = append(i.pluginMenus, newPluginMenu(p.Label))
+	}
+	if len(i.settings.WatchedDirectories) > 0 {
+		i.dirsMenu = newDirsMenu(i.settings.WatchedDirectories)
+	}
+
+	systray.AddSeparator()
+	i.infoMenu = newInfoMenu()
+	i.maintenanceMenu = newMaintenanceMenu()
 
 	systray.AddSeparator()
 	i.settingsItem = systray.AddMenuItem("Settings", "Configure the application")
@@ -85,6 +187,12 @@ func (i *Indicator) onReady() {
 
 	// Start handling events
 	go i.handleEvents()
+	for _, slot := range i.processSlots {
+		go slot.handleEvents(i.stopChan)
+	}
+	go i.removableMenu.handleEvents(i.stopChan)
+	go i.analyzerMenu.handleEvents(i.stopChan)
+	go i.cleanupMenu.handleEvents(i.stopChan)
 
 	// Initialize with default values to show something immediately
 	i.updateMetricsDisplay(0, 0, 0, metrics.NetworkUsage{})
@@ -100,6 +208,8 @@ func (i *Indicator) handleEvents() {
 		case <-i.settingsItem.ClickedCh:
 			log.Println("Settings clicked")
 			i.settingsWin.Show()
+		case <-i.groupByAppItem.ClickedCh:
+			i.toggleGroupByApp()
 		case <-i.quitItem.ClickedCh:
 			log.Println("Quit clicked")
 			systray.Quit()
@@ -118,6 +228,25 @@ func (i *Indicator) Stop() {
 	close(i.stopChan)
 }
 
+// toggleGroupByApp switches the top processes submenu between processes and applications
+func (i *Indicator) toggleGroupByApp() {
+	i.settings.GroupProcessesByApp = !i.settings.GroupProcessesByApp
+	if i.settings.GroupProcessesByApp {
+		i.groupByAppItem.Check()
+	} else {
+		i.groupByAppItem.Uncheck()
+	}
+
+	if err := i.settings.Save(); err != nil {
+		log.Printf("Failed to save settings: %v", err)
+	}
+
+	// Refresh straight away rather than waiting for the next tick
+	if i.onSettingsChanged != nil {
+		i.onSettingsChanged()
+	}
+}
+
 // onSettingsSaved is called when settings are saved
 func (i *Indicator) onSettingsSaved() {
 	log.Println("Settings saved, updating visibility")
@@ -165,6 +294,89 @@ func (i *Indicator) updateItemVisibility() {
 	} else {
 		i.diskItem.Hide()
 	}
+
+	// Drives are listed again on the next update once enabled
+	if !i.settings.ShowRemovableDrives {
+		i.removableMenu.update(nil)
+	}
+
+	if i.settings.ShowProcesses {
+		i.processesItem.Show()
+	} else {
+		i.processesItem.Hide()
+	}
+
+	if i.settings.ShowDiskIO {
+		i.diskIOItem.Show()
+	} else {
+		i.diskIOItem.Hide()
+	}
+
+	if i.settings.ShowUsers {
+		i.usersItem.Show()
+	} else {
+		i.usersItem.Hide()
+	}
+
+	if i.settings.ShowServices {
+		i.servicesItem.Show()
+	} else {
+		i.servicesItem.Hide()
+	}
+
+	if i.settings.ShowContainers {
+		i.containersItem.Show()
+	} else {
+		i.containersItem.Hide()
+	}
+
+	if i.settings.ShowKernel {
+		i.kernelMenu.item.Show()
+	} else {
+		i.kernelMenu.item.Hide()
+	}
+
+	if i.settings.ShowFileHandles {
+		i.filesMenu.item.Show()
+	} else {
+		i.filesMenu.item.Hide()
+	}
+
+	if i.settings.ShowSwapActivity {
+		i.swapMenu.item.Show()
+	} else {
+		i.swapMenu.item.Hide()
+	}
+
+	if i.settings.ShowStorageHealth {
+		i.storageMenu.item.Show()
+	} else {
+		i.storageMenu.item.Hide()
+	}
+
+	if i.settings.ShowDriveHealth && len(i.settings.SmartDevices) > 0 {
+		i.driveMenu.item.Show()
+	} else {
+		i.driveMenu.item.Hide()
+	}
+
+	if i.settings.ShowPower {
+		i.powerMenu.item.Show()
+	} else {
+		i.powerMenu.item.Hide()
+	}
+
+	if i.settings.ShowSystemInfo {
+		i.infoMenu.item.Show()
+	} else {
+		i.infoMenu.item.Hide()
+	}
+
+	if i.settings.ShowMaintenance {
+		i.maintenanceMenu.item.Show()
+	} else {
+		i.maintenanceMenu.item.Hide()
+	}
 }
 
 // UpdateMetrics updates the menu items with the latest metrics
@@ -190,12 +402,24 @@ func (i *Indicator) UpdateMetrics(cpuUsage, memUsage, diskUsage float64, netUsag
 
 // updateMetricsDisplay updates the UI components with metrics
 func (i *Indicator) updateMetricsDisplay(cpuUsage, memUsage, diskUsage float64, netUsage metrics.NetworkUsage) {
+	// Throttling is reported alongside CPU usage
+	i.mutex.Lock()
+	throttle := i.throttle
+	i.mutex.Unlock()
+	throttled := throttle != nil && throttle.Throttled && i.settings.DetectThrottling
+
 	// Create title with all requested metrics
 	var titleParts []string
 
 	if i.settings.ShowCPUInTitle && i.settings.ShowCPU {
 		cpuText := fmt.Sprintf("C:%.1f%%", cpuUsage)
+		if throttled {
+			cpuText += " ⚠ throttled"
+		}
 		titleParts = append(titleParts, cpuText)
+	} else if throttled {
+		// A throttled run is worth knowing about even when CPU usage isn't in the title
+		titleParts = append(titleParts, "⚠ throttled")
 	}
 
 	if i.settings.ShowMemoryInTitle && i.settings.ShowMemory {
@@ -220,6 +444,69 @@ func (i *Indicator) updateMetricsDisplay(cpuUsage, memUsage, diskUsage float64,
 		titleParts = append(titleParts, netText)
 	}
 
+	// Values collected by the other Update methods are guarded by the mutex
+	i.mutex.Lock()
+
+	if i.power != nil && i.settings.ShowPowerInTitle && i.settings.ShowPower {
+		titleParts = append(titleParts, fmt.Sprintf("P:%.1fW", i.power.PackageWatts))
+	}
+
+	// Warn when file handles or inotify watches are about to run out
+	if i.fileHandles != nil && i.settings.ShowFileHandles {
+		if warning := formatFileHandleWarning(*i.fileHandles, i.settings.FileHandleWarnPercent); warning != "" {
+			titleParts = append(titleParts, warning)
+		}
+	}
+
+	// A degraded array or read-only filesystem goes unnoticed unless it is in the title
+	if i.storageHealth != nil && i.settings.ShowStorageHealth {
+		if warning := formatStorageWarning(*i.storageHealth); warning != "" {
+			titleParts = append(titleParts, warning)
+		}
+	}
+
+	// Pending reboots, failed units and security updates are a nudge to act
+	if i.maintenance != nil && i.settings.ShowMaintenance {
+		if badge := formatMaintenanceBadge(*i.maintenance); badge != "" {
+			titleParts = append(titleParts, badge)
+		}
+	}
+
+	// Swap activity, rather than swap usage, is what makes the desktop unresponsive
+	if i.vmActivity != nil && i.settings.ShowSwapInTitle && i.settings.ShowSwapActivity {
+		titleParts = append(titleParts, formatSwapTitle(*i.vmActivity))
+	}
+
+	// Watched processes are shown in the title if requested for each watch
+	for n, w := range i.settings.ProcessWatches {
+		if n < len(i.watchStats) && w.ShowInTitle {
+			titleParts = append(titleParts, formatWatchTitle(i.watchStats[n]))
+		}
+	}
+
+	// Like watches, command and HTTP metrics are shown in the title if requested for each one
+	for n, c := range i.settings.CommandMetrics {
+		if n < len(i.commandValues) && c.ShowInTitle {
+			titleParts = append(titleParts, formatCustomTitle(i.commandValues[n]))
+		}
+	}
+	for n, h := range i.settings.HTTPMetrics {
+		if n < len(i.httpValues) && h.ShowInTitle {
+			titleParts = append(titleParts, formatCustomTitle(i.httpValues[n]))
+		}
+	}
+	for n, p := range i.settings.Plugins {
+		if n >= len(i.pluginStatuses) {
+			continue
+		}
+		for _, name := range p.ShowInTitle {
+			if value, ok := pluginMetric(i.pluginStatuses[n], name); ok {
+				titleParts = append(titleParts, formatCustomTitle(value))
+			}
+		}
+	}
+	i.mutex.Unlock()
+
 	// If no metrics selected for title, show a default
 	if len(titleParts) == 0 {
 		systray.SetTitle("Sys Monitor")
@@ -264,13 +551,26 @@ func (i *Indicator) updateMetricsDisplay(cpuUsage, memUsage, diskUsage float64,
 	log.Printf("Setting tooltip to: %s", tooltipText)
 	systray.SetTooltip(tooltipText)
 
+	// In container mode CPU and memory are relative to the cgroup limits
+	limitSuffix := ""
+	if metrics.ContainerMode() {
+		limitSuffix = " of container limit"
+	}
+
 	// Update individual menu items
 	if i.settings.ShowCPU {
-		i.cpuItem.SetTitle(fmt.Sprintf("CPU: %.1f%%", cpuUsage))
+		cpuText := fmt.Sprintf("CPU: %.1f%%%s", cpuUsage, limitSuffix)
+		if throttle != nil && throttle.FrequencyKnown && i.settings.DetectThrottling {
+			cpuText += fmt.Sprintf(" at %.1f of %.1f GHz", throttle.CurrentMHz/1000, throttle.MaxMHz/1000)
+		}
+		if throttled {
+			cpuText += fmt.Sprintf(" ⚠ throttled (%d events)", throttle.CoreEvents+throttle.PackageEvents)
+		}
+		i.cpuItem.SetTitle(cpuText)
 	}
 
 	if i.settings.ShowMemory {
-		i.memoryItem.SetTitle(fmt.Sprintf("Memory: %.1f%%", memUsage))
+		i.memoryItem.SetTitle(fmt.Sprintf("Memory: %.1f%%%s", memUsage, limitSuffix))
 	}
 
 	if i.settings.ShowDisk {
@@ -283,6 +583,423 @@ func (i *Indicator) updateMetricsDisplay(cpuUsage, memUsage, diskUsage float64,
 	}
 }
 
+// UpdateProcesses updates the top processes submenu
+func (i *Indicator) UpdateProcesses(procs []metrics.ProcessInfo) {
+	// Only update if ready
+	i.mutex.Lock()
+	ready := i.ready
+	i.mutex.Unlock()
+
+	if !ready {
+		return
+	}
+
+	for n, slot := range i.processSlots {
+		if n < len(procs) {
+			slot.update(&procs[n])
+		} else {
+			slot.update(nil)
+		}
+	}
+}
+
+// UpdateAppGroups updates the top processes submenu with applications instead of processes
+func (i *Indicator) UpdateAppGroups(groups []metrics.AppGroup) {
+	// Only update if ready
+	i.mutex.Lock()
+	ready := i.ready
+	i.mutex.Unlock()
+
+	if !ready {
+		return
+	}
+
+	for n, slot := range i.processSlots {
+		if n < len(groups) {
+			slot.updateGroup(&groups[n])
+		} else {
+			slot.updateGroup(nil)
+		}
+	}
+}
+
+// UpdateDiskIO updates the top disk I/O submenu
+func (i *Indicator) UpdateDiskIO(procs []metrics.ProcessIO) {
+	// Only update if ready
+	i.mutex.Lock()
+	ready := i.ready
+	i.mutex.Unlock()
+
+	if !ready {
+		return
+	}
+
+	for n, slot := range i.diskIOSlots {
+		switch {
+		case n < len(procs) && n < i.settings.TopProcessCount:
+			p := procs[n]
+			slot.SetTitle(fmt.Sprintf("%s (%d): R %s/s  W %s/s", p.Name, p.PID,
+				metrics.FormatBytes(uint64(p.ReadSpeed)), metrics.FormatBytes(uint64(p.WriteSpeed))))
+			slot.Show()
+		case n == 0:
+			slot.SetTitle("No disk activity")
+			slot.Show()
+		default:
+			slot.Hide()
+		}
+	}
+}
+
+// UpdateUsers updates the per-user usage submenu
+func (i *Indicator) UpdateUsers(users []metrics.UserUsage) {
+	// Only update if ready
+	i.mutex.Lock()
+	ready := i.ready
+	i.mutex.Unlock()
+
+	if !ready {
+		return
+	}
+
+	for n, slot := range i.userSlots {
+		if n < len(users) && n < i.settings.TopProcessCount {
+			u := users[n]
+			slot.SetTitle(fmt.Sprintf("%s: %.1f%% CPU, %s, %d processes",
+				u.Username, u.CPUPercent, metrics.FormatBytes(u.MemoryRSS), u.ProcessCount))
+			slot.Show()
+		} else {
+			slot.Hide()
+		}
+	}
+}
+
+// UpdateServices updates the services submenu and the pinned service items
+func (i *Indicator) UpdateServices(services []metrics.ServiceUsage) {
+	// Only update if ready
+	i.mutex.Lock()
+	ready := i.ready
+	i.mutex.Unlock()
+
+	if !ready {
+		return
+	}
+
+	for n, slot := range i.serviceSlots {
+		if n < len(services) && n < i.settings.TopProcessCount {
+			slot.SetTitle(formatServiceUsage(services[n]))
+			slot.Show()
+		} else {
+			slot.Hide()
+		}
+	}
+
+	// Pinned services without a cgroup are not running
+	for name, item := range i.pinnedItems {
+		if s, ok := metrics.FindService(services, name); ok {
+			item.SetTitle(formatServiceUsage(s))
+		} else {
+			item.SetTitle(name + ": not running")
+		}
+	}
+}
+
+// formatServiceUsage returns the menu form of a service's usage
+func formatServiceUsage(s metrics.ServiceUsage) string {
+	return fmt.Sprintf("%s: %.1f%% CPU, %s, R %s/s W %s/s", s.Name, s.CPUPercent, metrics.FormatBytes(s.Memory),
+		metrics.FormatBytes(uint64(s.ReadSpeed)), metrics.FormatBytes(uint64(s.WriteSpeed)))
+}
+
+// UpdateContainers updates the containers submenu
+func (i *Indicator) UpdateContainers(containers []metrics.ContainerStats) {
+	// Only update if ready
+	i.mutex.Lock()
+	ready := i.ready
+	i.mutex.Unlock()
+
+	if !ready {
+		return
+	}
+
+	for n, slot := range i.containerSlots {
+		switch {
+		case n < len(containers):
+			c := containers[n]
+			slot.SetTitle(fmt.Sprintf("%s: %.1f%% CPU, %s / %s, ↓%s/s ↑%s/s", c.Name, c.CPUPercent,
+				metrics.FormatBytes(c.MemoryUsage), metrics.FormatBytes(c.MemoryLimit),
+				metrics.FormatBytes(uint64(c.RxSpeed)), metrics.FormatBytes(uint64(c.TxSpeed))))
+			slot.SetTooltip(c.Image)
+			slot.Show()
+		case n == 0:
+			slot.SetTitle("No running containers")
+			slot.SetTooltip("")
+			slot.Show()
+		default:
+			slot.Hide()
+		}
+	}
+}
+
+// UpdateKernelActivity updates the kernel activity submenu
+func (i *Indicator) UpdateKernelActivity(activity metrics.KernelActivity) {
+	// Only update if ready
+	i.mutex.Lock()
+	ready := i.ready
+	i.mutex.Unlock()
+
+	if !ready {
+		return
+	}
+
+	i.kernelMenu.update(activity)
+}
+
+// UpdateFileHandles updates the file handle submenu.
+// A title warning is added on the next call to UpdateMetrics if a limit is nearly reached.
+func (i *Indicator) UpdateFileHandles(usage metrics.FileHandleUsage) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if !i.ready {
+		return
+	}
+
+	i.fileHandles = &usage
+	i.filesMenu.update(usage, i.settings.FileHandleWarnPercent)
+}
+
+// UpdateVMActivity updates the swap activity submenu.
+// The title picks up the new values on the next call to UpdateMetrics.
+func (i *Indicator) UpdateVMActivity(activity metrics.VMActivity) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if !i.ready {
+		return
+	}
+
+	i.vmActivity = &activity
+	i.swapMenu.update(activity)
+}
+
+// UpdateStorageHealth updates the storage health submenu.
+// A title warning is added on the next call to UpdateMetrics if something is wrong.
+func (i *Indicator) UpdateStorageHealth(health metrics.StorageHealth) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if !i.ready {
+		return
+	}
+
+	i.storageHealth = &health
+	i.storageMenu.update(health)
+}
+
+// UpdateDriveHealth updates the disk health submenu
+func (i *Indicator) UpdateDriveHealth(drives []metrics.DriveHealth) {
+	// Only update if ready
+	i.mutex.Lock()
+	ready := i.ready
+	i.mutex.Unlock()
+
+	if !ready {
+		return
+	}
+
+	i.driveMenu.update(drives)
+}
+
+// UpdatePower updates the CPU power submenu, or explains why power cannot be measured.
+// The title picks up the new value on the next call to UpdateMetrics.
+func (i *Indicator) UpdatePower(usage metrics.PowerUsage, err error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if !i.ready {
+		return
+	}
+
+	if err != nil {
+		i.power = nil
+	} else {
+		i.power = &usage
+	}
+	i.powerMenu.update(usage, err)
+}
+
+// UpdateThrottleStatus records whether the CPU was throttled during the last refresh.
+// The CPU item and title pick it up on the next call to UpdateMetrics.
+func (i *Indicator) UpdateThrottleStatus(status metrics.ThrottleStatus) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.throttle = &status
+}
+
+// UpdateMaintenance updates the maintenance submenu.
+// A title badge is added on the next call to UpdateMetrics if action is needed.
+func (i *Indicator) UpdateMaintenance(status metrics.MaintenanceStatus) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if !i.ready {
+		return
+	}
+
+	i.maintenance = &status
+	i.maintenanceMenu.update(status)
+}
+
+// UpdateRemovableDrives lists the mounted removable drives in the disk submenu
+func (i *Indicator) UpdateRemovableDrives(drives []metrics.RemovableDrive) {
+	// Only update if ready
+	i.mutex.Lock()
+	ready := i.ready
+	i.mutex.Unlock()
+
+	if !ready {
+		return
+	}
+
+	i.removableMenu.update(drives)
+}
+
+// UpdateMountPoints offers the mount points for disk usage analysis. It is called
+// on every refresh, but the menu only changes when the mount table has.
+func (i *Indicator) UpdateMountPoints(mounts []string) {
+	// Only update if ready
+	i.mutex.Lock()
+	ready := i.ready
+	i.mutex.Unlock()
+
+	if !ready {
+		return
+	}
+
+	i.analyzerMenu.setMounts(mounts)
+}
+
+// UpdateDirectories updates the watched directories submenu
+func (i *Indicator) UpdateDirectories(dirs []metrics.DirectorySize) {
+	// Only update if ready
+	i.mutex.Lock()
+	ready := i.ready
+	i.mutex.Unlock()
+
+	if !ready || i.dirsMenu == nil {
+		return
+	}
+
+	i.dirsMenu.update(dirs)
+}
+
+// UpdateSystemInfo updates the system information submenu
+func (i *Indicator) UpdateSystemInfo(info metrics.SystemInfo) {
+	// Only update if ready
+	i.mutex.Lock()
+	ready := i.ready
+	i.mutex.Unlock()
+
+	if !ready {
+		return
+	}
+
+	i.infoMenu.update(info)
+}
+
+// UpdateWatchedProcesses updates the menu items for watched processes. There is
+// one entry per configured watch, in config order, including watches that
+// couldn't be set up. The title picks up the new values on the next call to UpdateMetrics.
+func (i *Indicator) UpdateWatchedProcesses(stats []metrics.WatchedProcessStats) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if !i.ready {
+		return
+	}
+
+	i.watchStats = stats
+	for n, s := range stats {
+		if n < len(i.watchItems) {
+			i.watchItems[n].SetTitle(formatWatchDetails(s))
+		}
+	}
+}
+
+// UpdateCustomMetrics updates the menu items for user-defined metrics. There is
+// one value per configured command and HTTP metric, in config order, including
+// metrics that couldn't be set up. The title picks up the new values on the next
+// call to UpdateMetrics.
+func (i *Indicator) UpdateCustomMetrics(commands, endpoints []metrics.CustomMetricValue) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if !i.ready {
+		return
+	}
+
+	i.commandValues = commands
+	for n, v := range commands {
+		if n < len(i.commandItems) {
+			i.commandItems[n].SetTitle(formatCustomDetails(v))
+		}
+	}
+	i.httpValues = endpoints
+	for n, v := range endpoints {
+		if n < len(i.httpItems) {
+			i.httpItems[n].SetTitle(formatCustomDetails(v))
+		}
+	}
+}
+
+// UpdatePlugins updates the plugin submenus. There is one status per configured
+// plugin, in config order, including plugins that couldn't be set up. The title
+// picks up the new values on the next call to UpdateMetrics.
+func (i *Indicator) UpdatePlugins(statuses []metrics.PluginStatus) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if !i.ready {
+		return
+	}
+
+	i.pluginStatuses = statuses
+	for n, s := range statuses {
+		if n < len(i.pluginMenus) {
+			i.pluginMenus[n].update(s)
+		}
+	}
+}
+
+// formatWatchTitle returns the short taskbar form of a watched process, e.g. "postgres: 3.2% 1.1 GB"
+func formatWatchTitle(s metrics.WatchedProcessStats) string {
+	if s.Error != "" {
+		return s.Label + ": ⚠"
+	}
+	if !s.Running {
+		return s.Label + ": not running"
+	}
+	return fmt.Sprintf("%s: %.1f%% %s", s.Label, s.CPUPercent, metrics.FormatBytes(s.MemoryRSS))
+}
+
+// formatWatchDetails returns the menu form of a watched process
+func formatWatchDetails(s metrics.WatchedProcessStats) string {
+	if s.Error != "" {
+		return fmt.Sprintf("%s: ⚠ %s", s.Label, s.Error)
+	}
+	if !s.Running {
+		return s.Label + ": not running"
+	}
+
+	fds := "? FDs"
+	if s.NumFDs >= 0 {
+		fds = fmt.Sprintf("%d FDs", s.NumFDs)
+	}
+
+	return fmt.Sprintf("%s: %.1f%% CPU, %s, %d threads, %s, up %s",
+		s.Label, s.CPUPercent, metrics.FormatBytes(s.MemoryRSS), s.NumThreads, fds, metrics.FormatDuration(s.Uptime))
+}
+
 // onExit is called when the systray is exiting
 func (i *Indicator) onExit() {
 	log.Println("Exiting system monitor")
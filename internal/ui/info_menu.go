@@ -0,0 +1,100 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/casper9429-kth/task_bar_monitor/internal/metrics"
+	"github.com/getlantern/systray"
+)
+
+// maxSessions is the number of login session slots created in the menu
+const maxSessions = 10
+
+// infoMenu is the submenu showing the machine's identity and login sessions
+type infoMenu struct {
+	item         *systray.MenuItem
+	kernelItem   *systray.MenuItem
+	distroItem   *systray.MenuItem
+	uptimeItem   *systray.MenuItem
+	bootItem     *systray.MenuItem
+	clockItem    *systray.MenuItem
+	sessionsItem *systray.MenuItem
+	sessionSlots []*systray.MenuItem
+}
+
+// newInfoMenu creates the system information submenu
+func newInfoMenu() *infoMenu {
+	m := &infoMenu{
+		item: systray.AddMenuItem("System: Loading...", "System information"),
+	}
+	m.kernelItem = m.item.AddSubMenuItem("Kernel: Loading...", "Kernel version")
+	m.distroItem = m.item.AddSubMenuItem("Distribution: Loading...", "Operating system")
+	m.uptimeItem = m.item.AddSubMenuItem("Uptime: Loading...", "Time since boot")
+	m.bootItem = m.item.AddSubMenuItem("Booted: Loading...", "Boot time")
+	m.clockItem = m.item.AddSubMenuItem("Clock: Loading...", "System clock synchronization")
+	m.sessionsItem = m.item.AddSubMenuItem("Sessions: Loading...", "Active login sessions")
+	for n := 0; n < maxSessions; n++ {
+		slot := m.sessionsItem.AddSubMenuItem("", "Login session")
+		slot.Hide()
+		m.sessionSlots = append(m.sessionSlots, slot)
+	}
+	return m
+}
+
+// update shows the latest system information
+func (m *infoMenu) update(info metrics.SystemInfo) {
+	remote := 0
+	for _, s := range info.Sessions {
+		if s.Remote() {
+			remote++
+		}
+	}
+
+	// Remote logins are surfaced on the top-level item since they may be unexpected
+	title := "System: " + info.Hostname
+	if remote > 0 {
+		title += fmt.Sprintf(" ⚠ %d remote session(s)", remote)
+	}
+	if info.Clock != nil && !info.Clock.Synchronized {
+		title += " ⚠ clock not synchronized"
+	}
+	m.item.SetTitle(title)
+
+	m.kernelItem.SetTitle("Kernel: " + info.Kernel)
+	m.distroItem.SetTitle(info.Distro)
+	m.uptimeItem.SetTitle("Uptime: " + metrics.FormatDuration(info.Uptime))
+	m.bootItem.SetTitle("Booted: " + info.BootTime.Format("2006-01-02 15:04"))
+	m.sessionsItem.SetTitle(fmt.Sprintf("Sessions: %d", len(info.Sessions)))
+
+	if info.Clock != nil {
+		m.clockItem.SetTitle(formatClockSync(*info.Clock))
+		m.clockItem.Show()
+	} else {
+		m.clockItem.Hide()
+	}
+
+	for n, slot := range m.sessionSlots {
+		if n >= len(info.Sessions) {
+			slot.Hide()
+			continue
+		}
+
+		s := info.Sessions[n]
+		text := fmt.Sprintf("%s on %s", s.User, s.Terminal)
+		if s.Remote() {
+			text = fmt.Sprintf("⚠ %s from %s", text, s.Host)
+		}
+		slot.SetTitle(fmt.Sprintf("%s since %s", text, s.Started.Format("Jan 2 15:04")))
+		slot.Show()
+	}
+}
+
+// formatClockSync describes the clock state, e.g. "Clock: synchronized, offset 120µs, error ±1.2ms"
+func formatClockSync(c metrics.ClockSync) string {
+	if !c.Synchronized {
+		return fmt.Sprintf("⚠ Clock: not synchronized, error up to %s", c.MaxError.Round(time.Millisecond))
+	}
+	return fmt.Sprintf("Clock: synchronized, offset %s, error ±%s",
+		c.Offset.Round(time.Microsecond), c.EstimatedError.Round(time.Microsecond))
+}
@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/casper9429-kth/task_bar_monitor/internal/metrics"
+	"github.com/getlantern/systray"
+)
+
+// kernelMenu is the submenu showing scheduler activity and process counts
+type kernelMenu struct {
+	item           *systray.MenuItem
+	contextItem    *systray.MenuItem
+	interruptsItem *systray.MenuItem
+	forksItem      *systray.MenuItem
+	processesItem  *systray.MenuItem
+	zombiesItem    *systray.MenuItem
+}
+
+// newKernelMenu creates the kernel activity submenu
+func newKernelMenu() *kernelMenu {
+	m := &kernelMenu{
+		item: systray.AddMenuItem("Kernel: Loading...", "Kernel activity"),
+	}
+	m.contextItem = m.item.AddSubMenuItem("Context switches: Loading...", "Context switches per second")
+	m.interruptsItem = m.item.AddSubMenuItem("Interrupts: Loading...", "Interrupts per second")
+	m.forksItem = m.item.AddSubMenuItem("Forks: Loading...", "New processes per second")
+	m.processesItem = m.item.AddSubMenuItem("Processes: Loading...", "Process counts")
+	m.zombiesItem = m.item.AddSubMenuItem("Zombies: Loading...", "Exited processes not reaped by their parent")
+	return m
+}
+
+// update shows the latest kernel activity
+func (m *kernelMenu) update(k metrics.KernelActivity) {
+	// Fork loops and zombie build-up are the things worth noticing at a glance
+	title := fmt.Sprintf("Kernel: %s forks/s", formatCount(k.ForkRate))
+	if k.Zombies > 0 {
+		title += fmt.Sprintf(" ⚠ %d zombies", k.Zombies)
+	}
+	m.item.SetTitle(title)
+
+	m.contextItem.SetTitle(fmt.Sprintf("Context switches: %s/s", formatCount(k.ContextSwitchRate)))
+	m.interruptsItem.SetTitle(fmt.Sprintf("Interrupts: %s/s", formatCount(k.InterruptRate)))
+	m.forksItem.SetTitle(fmt.Sprintf("Forks: %s/s", formatCount(k.ForkRate)))
+	m.processesItem.SetTitle(fmt.Sprintf("Processes: %d (%d running, %d blocked)", k.Processes, k.Running, k.Blocked))
+	m.zombiesItem.SetTitle(fmt.Sprintf("Zombies: %d", k.Zombies))
+}
+
+// formatCount returns a compact representation of a rate, e.g. "12.3k"
+func formatCount(v float64) string {
+	switch {
+	case v >= 1e6:
+		return fmt.Sprintf("%.1fM", v/1e6)
+	case v >= 1e3:
+		return fmt.Sprintf("%.1fk", v/1e3)
+	default:
+		return fmt.Sprintf("%.0f", v)
+	}
+}
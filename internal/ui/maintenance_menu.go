@@ -0,0 +1,108 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/casper9429-kth/task_bar_monitor/internal/metrics"
+	"github.com/getlantern/systray"
+)
+
+// maxFailedUnits is the number of failed systemd units listed in the maintenance submenu
+const maxFailedUnits = 10
+
+// maintenanceMenu is the submenu showing pending reboots, failed units and updates
+type maintenanceMenu struct {
+	item        *systray.MenuItem
+	rebootItem  *systray.MenuItem
+	unitsItem   *systray.MenuItem
+	unitSlots   []*systray.MenuItem
+	updatesItem *systray.MenuItem
+}
+
+// newMaintenanceMenu creates the maintenance submenu
+func newMaintenanceMenu() *maintenanceMenu {
+	m := &maintenanceMenu{
+		item: systray.AddMenuItem("Maintenance: Loading...", "Pending administrative tasks"),
+	}
+	m.rebootItem = m.item.AddSubMenuItem("Reboot: Loading...", "Whether installed updates need a reboot")
+	m.unitsItem = m.item.AddSubMenuItem("Failed units: Loading...", "systemd units in the failed state")
+	for n := 0; n < maxFailedUnits; n++ {
+		slot := m.unitsItem.AddSubMenuItem("", "Failed unit")
+		slot.Hide()
+		m.unitSlots = append(m.unitSlots, slot)
+	}
+	m.updatesItem = m.item.AddSubMenuItem("Updates: Loading...", "Package updates reported by update-notifier")
+	return m
+}
+
+// update shows the latest maintenance status
+func (m *maintenanceMenu) update(s metrics.MaintenanceStatus) {
+	if badge := formatMaintenanceBadge(s); badge != "" {
+		m.item.SetTitle("Maintenance: " + badge)
+	} else {
+		m.item.SetTitle("Maintenance: nothing to do")
+	}
+
+	if s.RebootRequired {
+		title := "⚠ Reboot required"
+		if len(s.RebootPackages) > 0 {
+			title += " by " + strings.Join(s.RebootPackages, ", ")
+		}
+		m.rebootItem.SetTitle(title)
+	} else {
+		m.rebootItem.SetTitle("No reboot required")
+	}
+
+	switch {
+	case s.UnitsError != "":
+		m.unitsItem.SetTitle("Failed units: unknown")
+		m.unitsItem.SetTooltip(s.UnitsError)
+	case len(s.FailedUnits) > 0:
+		m.unitsItem.SetTitle(fmt.Sprintf("⚠ Failed units: %d", len(s.FailedUnits)))
+		m.unitsItem.SetTooltip("systemd units in the failed state")
+	default:
+		m.unitsItem.SetTitle("Failed units: none")
+		m.unitsItem.SetTooltip("systemd units in the failed state")
+	}
+	for n, slot := range m.unitSlots {
+		if n < len(s.FailedUnits) {
+			u := s.FailedUnits[n]
+			slot.SetTitle(fmt.Sprintf("%s: %s", u.Unit, u.Description))
+			slot.Show()
+		} else {
+			slot.Hide()
+		}
+	}
+
+	switch {
+	case s.PendingUpdates < 0:
+		m.updatesItem.Hide()
+	case s.SecurityUpdates > 0:
+		m.updatesItem.SetTitle(fmt.Sprintf("⚠ Updates: %d pending, %d security", s.PendingUpdates, s.SecurityUpdates))
+		m.updatesItem.Show()
+	default:
+		m.updatesItem.SetTitle(fmt.Sprintf("Updates: %d pending", s.PendingUpdates))
+		m.updatesItem.Show()
+	}
+}
+
+// formatMaintenanceBadge returns a short summary of what needs attention, e.g. "⚠ reboot, 2 failed", or ""
+func formatMaintenanceBadge(s metrics.MaintenanceStatus) string {
+	if !s.ActionNeeded() {
+		return ""
+	}
+
+	var parts []string
+	if s.RebootRequired {
+		parts = append(parts, "reboot")
+	}
+	if len(s.FailedUnits) > 0 {
+		parts = append(parts, fmt.Sprintf("%d failed", len(s.FailedUnits)))
+	}
+	if s.SecurityUpdates > 0 {
+		parts = append(parts, fmt.Sprintf("%d security", s.SecurityUpdates))
+	}
+
+	return "⚠ " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/casper9429-kth/task_bar_monitor/internal/metrics"
+	"github.com/getlantern/systray"
+)
+
+// maxPluginMetrics is the number of metrics listed for each plugin
+const maxPluginMetrics = 10
+
+// pluginMenu is the submenu showing a plugin's state and the metrics it reports
+type pluginMenu struct {
+	label      string
+	item       *systray.MenuItem
+	statusItem *systray.MenuItem
+	slots      []*systray.MenuItem
+}
+
+// newPluginMenu creates the submenu for a plugin. Its metrics are only known once
+// the plugin has started, so the entries for them are filled in by update.
+func newPluginMenu(label string) *pluginMenu {
+	m := &pluginMenu{
+		label: label,
+		item:  systray.AddMenuItem(label, "Plugin"),
+	}
+	m.statusItem = m.item.AddSubMenuItem("Starting...", "Plugin process")
+	m.statusItem.Disable()
+	for n := 0; n < maxPluginMetrics; n++ {
+		slot := m.item.AddSubMenuItem("", "Plugin metric")
+		slot.Hide()
+		m.slots = append(m.slots, slot)
+	}
+	return m
+}
+
+// update shows the plugin's state and latest values
+func (m *pluginMenu) update(s metrics.PluginStatus) {
+	failed := s.Error != "" || (!s.Running && !s.NextRestart.IsZero())
+	if failed {
+		m.item.SetTitle("⚠ " + m.label)
+	} else {
+		m.item.SetTitle(m.label)
+	}
+
+	m.statusItem.SetTitle(formatPluginStatus(s))
+	if s.LastCrash != "" {
+		m.statusItem.SetTooltip(fmt.Sprintf("Last crash at %s: %s", s.CrashedAt.Format("15:04:05"), s.LastCrash))
+	}
+
+	for n, slot := range m.slots {
+		if n >= len(s.Metrics) {
+			slot.Hide()
+			continue
+		}
+
+		metric := s.Metrics[n]
+		slot.SetTitle(formatCustomDetails(metric.CustomMetricValue))
+		if metric.Min != nil && metric.Max != nil {
+			slot.SetTooltip(fmt.Sprintf("%s, from %s to %s", metric.Name,
+				formatCustomValue(*metric.Min, metric.Unit), formatCustomValue(*metric.Max, metric.Unit)))
+		} else {
+			slot.SetTooltip(metric.Name)
+		}
+		slot.Show()
+	}
+}
+
+// formatPluginStatus describes whether a plugin is running, e.g. "Running since 09:12, restarted 2 times"
+func formatPluginStatus(s metrics.PluginStatus) string {
+	switch {
+	case s.Error != "":
+		return "⚠ " + s.Error
+	case s.Running:
+		text := "Running since " + s.Started.Format("15:04")
+		if s.Restarts > 0 {
+			text += fmt.Sprintf(", restarted %d %s", s.Restarts, plural(s.Restarts, "time", "times"))
+		}
+		return text
+	case !s.NextRestart.IsZero():
+		return fmt.Sprintf("⚠ Crashed: %s; restarting at %s", s.LastCrash, s.NextRestart.Format("15:04:05"))
+	}
+	return "Starting..."
+}
+
+// pluginMetric returns the value of the named metric reported by a plugin
+func pluginMetric(s metrics.PluginStatus, name string) (metrics.CustomMetricValue, bool) {
+	for _, m := range s.Metrics {
+		if m.Name == name {
+			return m.CustomMetricValue, true
+		}
+	}
+	return metrics.CustomMetricValue{}, false
+}
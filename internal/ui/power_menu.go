@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/casper9429-kth/task_bar_monitor/internal/metrics"
+	"github.com/getlantern/systray"
+)
+
+// maxPowerZones is the number of RAPL domains listed in the power submenu
+const maxPowerZones = 8
+
+// powerMenu is the submenu showing CPU power draw by RAPL domain
+type powerMenu struct {
+	item  *systray.MenuItem
+	slots []*systray.MenuItem
+}
+
+// newPowerMenu creates the CPU power submenu
+func newPowerMenu() *powerMenu {
+	m := &powerMenu{
+		item: systray.AddMenuItem("CPU Power: Loading...", "CPU power draw measured by RAPL"),
+	}
+	for n := 0; n < maxPowerZones; n++ {
+		slot := m.item.AddSubMenuItem("", "RAPL domain power")
+		slot.Hide()
+		m.slots = append(m.slots, slot)
+	}
+	return m
+}
+
+// update shows the latest power draw, or why it cannot be measured
+func (m *powerMenu) update(p metrics.PowerUsage, err error) {
+	if err != nil {
+		switch {
+		case errors.Is(err, metrics.ErrPermissionDenied):
+			m.item.SetTitle("CPU Power: needs read access to RAPL counters")
+		case errors.Is(err, metrics.ErrNoRAPL):
+			m.item.SetTitle("CPU Power: not supported by this CPU")
+		default:
+			m.item.SetTitle("CPU Power: unavailable")
+		}
+		m.item.SetTooltip(err.Error())
+		for _, slot := range m.slots {
+			slot.Hide()
+		}
+		return
+	}
+
+	m.item.SetTitle(fmt.Sprintf("CPU Power: %.1f W", p.PackageWatts))
+	m.item.SetTooltip("CPU power draw measured by RAPL")
+
+	for n, slot := range m.slots {
+		if n < len(p.Zones) {
+			slot.SetTitle(fmt.Sprintf("%s: %.1f W", p.Zones[n].Name, p.Zones[n].Watts))
+			slot.Show()
+		} else {
+			slot.Hide()
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/casper9429-kth/task_bar_monitor/internal/metrics"
+	"github.com/getlantern/systray"
+)
+
+const (
+	// maxTopProcesses is the number of process slots created in the menu
+	maxTopProcesses = 10
+
+	// maxContainers is the number of container slots created in the menu
+	maxContainers = 20
+
+	// confirmTimeout is how long a process action waits for its confirming click
+	confirmTimeout = 10 * time.Second
+)
+
+// processAction identifies an action that can be taken on a process
+type processAction int
+
+const (
+	actionTerminate processAction = iota
+	actionKill
+	actionRenice
+)
+
+// pendingAction is an action waiting for the user to confirm it
+type pendingAction struct {
+	action  processAction
+	process metrics.ProcessInfo
+	expires time.Time
+}
+
+// processSlot is a menu entry for one of the top processes
+type processSlot struct {
+	item       *systray.MenuItem
+	statusItem *systray.MenuItem
+	termItem   *systray.MenuItem
+	killItem   *systray.MenuItem
+	reniceItem *systray.MenuItem
+	process    metrics.ProcessInfo
+	pending    *pendingAction
+	mutex      sync.Mutex
+}
+
+// newProcessSlot creates the menu entry and action items for a process slot
+func newProcessSlot(parent *systray.MenuItem) *processSlot {
+	slot := &processSlot{
+		item: parent.AddSubMenuItem("", "Process actions"),
+	}
+	slot.statusItem = slot.item.AddSubMenuItem("", "Result of the last action")
+	slot.statusItem.Disable()
+	slot.statusItem.Hide()
+	slot.termItem = slot.item.AddSubMenuItem("", "Ask the process to exit")
+	slot.killItem = slot.item.AddSubMenuItem("", "Stop the process immediately")
+	slot.reniceItem = slot.item.AddSubMenuItem("", "Give the process less CPU time")
+	slot.resetActionTitles()
+	slot.item.Hide()
+	return slot
+}
+
+// resetActionTitles restores the action items to their unconfirmed titles
+func (s *processSlot) resetActionTitles() {
+	s.termItem.SetTitle("Terminate (SIGTERM)")
+	s.killItem.SetTitle("Kill (SIGKILL)")
+	s.reniceItem.SetTitle(fmt.Sprintf("Lower priority (nice +%d)", metrics.ReniceStep))
+}
+
+// actionItem returns the menu item for an action
+func (s *processSlot) actionItem(action processAction) *systray.MenuItem {
+	switch action {
+	case actionKill:
+		return s.killItem
+	case actionRenice:
+		return s.reniceItem
+	default:
+		return s.termItem
+	}
+}
+
+// update shows a process in the slot, or hides the slot if there is none
+func (s *processSlot) update(proc *metrics.ProcessInfo) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Drop confirmations that were never completed
+	if s.pending != nil && time.Now().After(s.pending.expires) {
+		s.pending = nil
+		s.resetActionTitles()
+	}
+
+	if proc == nil {
+		s.item.Hide()
+		return
+	}
+
+	if proc.PID != s.process.PID {
+		s.statusItem.Hide()
+	}
+
+	s.process = *proc
+	s.item.SetTitle(fmt.Sprintf("%s (%d): %.1f%% CPU, %s",
+		proc.Name, proc.PID, proc.CPUPercent, metrics.FormatBytes(proc.MemoryRSS)))
+	s.termItem.Show()
+	s.killItem.Show()
+	s.reniceItem.Show()
+	s.item.Show()
+}
+
+// updateGroup shows an application group in the slot, or hides the slot if there is none.
+// Process actions only apply to single processes, so they are hidden for groups.
+func (s *processSlot) updateGroup(group *metrics.AppGroup) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.pending = nil
+	s.resetActionTitles()
+	s.process = metrics.ProcessInfo{}
+	s.statusItem.Hide()
+	s.termItem.Hide()
+	s.killItem.Hide()
+	s.reniceItem.Hide()
+
+	if group == nil {
+		s.item.Hide()
+		return
+	}
+
+	s.item.SetTitle(fmt.Sprintf("%s (%d processes): %.1f%% CPU, %s",
+		group.Name, group.ProcessCount, group.CPUPercent, metrics.FormatBytes(group.Memory)))
+	s.item.Show()
+}
+
+// handleClick asks for confirmation on the first click and runs the action on the second
+func (s *processSlot) handleClick(action processAction) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.pending == nil || s.pending.action != action || time.Now().After(s.pending.expires) {
+		s.resetActionTitles()
+		s.pending = &pendingAction{
+			action:  action,
+			process: s.process,
+			expires: time.Now().Add(confirmTimeout),
+		}
+		s.actionItem(action).SetTitle(fmt.Sprintf("Click again to confirm (%s %d)", s.process.Name, s.process.PID))
+		return
+	}
+
+	// Act on the process that was shown when the action was first clicked,
+	// even if the list has been reordered since then
+	proc := s.pending.process
+	s.pending = nil
+	s.resetActionTitles()
+
+	var status string
+	var err error
+	switch action {
+	case actionTerminate:
+		err = metrics.TerminateProcess(proc.PID)
+		status = fmt.Sprintf("Sent SIGTERM to %s (%d)", proc.Name, proc.PID)
+	case actionKill:
+		err = metrics.KillProcess(proc.PID)
+		status = fmt.Sprintf("Sent SIGKILL to %s (%d)", proc.Name, proc.PID)
+	case actionRenice:
+		var nice int32
+		nice, err = metrics.LowerProcessPriority(proc.PID)
+		status = fmt.Sprintf("%s (%d) is now at nice %d", proc.Name, proc.PID, nice)
+	}
+
+	if err != nil {
+		log.Printf("Process action failed: %v", err)
+		if errors.Is(err, metrics.ErrPermissionDenied) {
+			status = fmt.Sprintf("⚠ Permission denied for %s (%d)", proc.Name, proc.PID)
+		} else {
+			status = "⚠ " + err.Error()
+		}
+	} else {
+		log.Println(status)
+	}
+
+	s.statusItem.SetTitle(status)
+	s.statusItem.Show()
+}
+
+// handleEvents processes clicks on the slot's action items
+func (s *processSlot) handleEvents(stopChan chan struct{}) {
+	for {
+		select {
+		case <-s.termItem.ClickedCh:
+			s.handleClick(actionTerminate)
+		case <-s.killItem.ClickedCh:
+			s.handleClick(actionKill)
+		case <-s.reniceItem.ClickedCh:
+			s.handleClick(actionRenice)
+		case <-stopChan:
+			return
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/casper9429-kth/task_bar_monitor/internal/metrics"
+	"github.com/getlantern/systray"
+)
+
+// maxRemovableDrives is the number of removable drives listed in the disk submenu
+const maxRemovableDrives = 6
+
+// removableSlot is a menu entry for a removable drive with its actions
+type removableSlot struct {
+	item        *systray.MenuItem
+	openItem    *systray.MenuItem
+	unmountItem *systray.MenuItem
+}
+
+// removableMenu lists mounted USB drives and SD cards in the disk submenu
+type removableMenu struct {
+	slots      []*removableSlot
+	drives     []metrics.RemovableDrive
+	unmounting map[string]bool   // Devices being unmounted
+	failures   map[string]string // Why unmounting a device failed
+	mutex      sync.Mutex
+}
+
+// newRemovableMenu adds hidden entries for removable drives to parent
+func newRemovableMenu(parent *systray.MenuItem) *removableMenu {
+	m := &removableMenu{
+		unmounting: make(map[string]bool),
+		failures:   make(map[string]string),
+	}
+	for n := 0; n < maxRemovableDrives; n++ {
+		slot := &removableSlot{item: parent.AddSubMenuItem("", "Removable drive")}
+		slot.openItem = slot.item.AddSubMenuItem("Open", "Open the drive in the file manager")
+		slot.unmountItem = slot.item.AddSubMenuItem("Unmount", "Unmount the drive so it can be unplugged safely")
+		slot.item.Hide()
+		m.slots = append(m.slots, slot)
+	}
+	return m
+}
+
+// handleEvents opens and unmounts drives as their items are clicked
+func (m *removableMenu) handleEvents(stopChan chan struct{}) {
+	for n, slot := range m.slots {
+		go func(n int, slot *removableSlot) {
+			for {
+				select {
+				case <-slot.openItem.ClickedCh:
+					m.open(n)
+				case <-slot.unmountItem.ClickedCh:
+					m.unmount(n)
+				case <-stopChan:
+					return
+				}
+			}
+		}(n, slot)
+	}
+}
+
+// update shows the mounted removable drives, hiding the entries of drives that were unplugged
+func (m *removableMenu) update(drives []metrics.RemovableDrive) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(drives) > maxRemovableDrives {
+		drives = drives[:maxRemovableDrives]
+	}
+	m.drives = drives
+
+	mounted := make(map[string]bool)
+	for n, slot := range m.slots {
+		if n >= len(drives) {
+			slot.item.Hide()
+			continue
+		}
+
+		d := drives[n]
+		mounted[d.Device] = true
+		slot.item.SetTitle(formatRemovableDrive(d))
+		slot.item.SetTooltip(fmt.Sprintf("%s (%s) mounted at %s", d.Device, d.FSType, d.MountPoint))
+		m.showUnmountState(slot, d.Device)
+		slot.item.Show()
+	}
+
+	// Forget about drives that were unplugged
+	for device := range m.unmounting {
+		if !mounted[device] {
+			delete(m.unmounting, device)
+		}
+	}
+	for device := range m.failures {
+		if !mounted[device] {
+			delete(m.failures, device)
+		}
+	}
+}
+
+// showUnmountState sets the unmount item of a slot for whether its device is being or failed to be unmounted
+func (m *removableMenu) showUnmountState(slot *removableSlot, device string) {
+	switch {
+	case m.unmounting[device]:
+		slot.unmountItem.SetTitle("Unmounting…")
+		slot.unmountItem.Disable()
+	case m.failures[device] != "":
+		slot.unmountItem.SetTitle(fmt.Sprintf("Unmount (⚠ %s)", m.failures[device]))
+		slot.unmountItem.Enable()
+	default:
+		slot.unmountItem.SetTitle("Unmount")
+		slot.unmountItem.Enable()
+	}
+}
+
+// open shows the drive in slot n in the file manager
+func (m *removableMenu) open(n int) {
+	m.mutex.Lock()
+	if n >= len(m.drives) {
+		m.mutex.Unlock()
+		return
+	}
+	d := m.drives[n]
+	m.mutex.Unlock()
+
+	if err := metrics.OpenPath(d.MountPoint); err != nil {
+		log.Printf("Failed to open %s: %v", d.MountPoint, err)
+	}
+}
+
+// unmount unmounts the drive in slot n in the background; the drive disappears
+// from the menu once the mount table changes
+func (m *removableMenu) unmount(n int) {
+	m.mutex.Lock()
+	if n >= len(m.drives) || m.unmounting[m.drives[n].Device] {
+		m.mutex.Unlock()
+		return
+	}
+	d := m.drives[n]
+	m.unmounting[d.Device] = true
+	delete(m.failures, d.Device)
+	m.showUnmountState(m.slots[n], d.Device)
+	m.mutex.Unlock()
+
+	go func() {
+		log.Printf("Unmounting %s from %s", d.Device, d.MountPoint)
+		err := metrics.UnmountDrive(d.Device)
+
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+
+		delete(m.unmounting, d.Device)
+		if err != nil {
+			log.Printf("Failed to unmount %s: %v", d.Device, err)
+			m.failures[d.Device] = err.Error()
+		}
+		for n, drive := range m.drives {
+			if drive.Device == d.Device {
+				m.showUnmountState(m.slots[n], d.Device)
+			}
+		}
+	}()
+}
+
+// formatRemovableDrive returns a drive's menu title, e.g. "Removable: BACKUP 12.3 GB of 32.0 GB (38.4%)"
+func formatRemovableDrive(d metrics.RemovableDrive) string {
+	switch {
+	case d.Error != "":
+		return fmt.Sprintf("Removable: %s ⚠ %s", d.Label, d.Error)
+	case d.Total == 0:
+		return fmt.Sprintf("Removable: %s", d.Label)
+	}
+	return fmt.Sprintf("Removable: %s %s of %s (%.1f%%)", d.Label,
+		metrics.FormatBytes(d.Used), metrics.FormatBytes(d.Total), d.UsedPercent)
+}
@@ -14,11 +14,28 @@ type SettingsWindow struct {
 	memoryCheck           *ui.Checkbox
 	networkCheck          *ui.Checkbox
 	diskCheck             *ui.Checkbox
+	processesCheck        *ui.Checkbox
+	diskIOCheck           *ui.Checkbox
+	usersCheck            *ui.Checkbox
+	servicesCheck         *ui.Checkbox
+	containersCheck       *ui.Checkbox
+	systemInfoCheck       *ui.Checkbox
+	kernelCheck           *ui.Checkbox
+	fileHandlesCheck      *ui.Checkbox
+	swapCheck             *ui.Checkbox
+	storageCheck          *ui.Checkbox
+	driveHealthCheck      *ui.Checkbox
+	powerCheck            *ui.Checkbox
+	throttleCheck         *ui.Checkbox
+	maintenanceCheck      *ui.Checkbox
+	removableCheck        *ui.Checkbox
 	cpuTitleCheck         *ui.Checkbox
 	memoryTitleCheck      *ui.Checkbox
 	networkTitleCheck     *ui.Checkbox
 	networkFullSpeedCheck *ui.Checkbox
 	diskTitleCheck        *ui.Checkbox
+	swapTitleCheck        *ui.Checkbox
+	powerTitleCheck       *ui.Checkbox
 	refreshIntervalEntry  *ui.Spinbox
 	saveButton            *ui.Button
 	cancelButton          *ui.Button
@@ -39,7 +56,7 @@ func NewSettingsWindow(appSettings *settings.Config, onSaved func()) *SettingsWi
 // initUI initializes the settings window UI
 func (sw *SettingsWindow) initUI() {
 	// Create a smaller window now that we don't have tabs
-	sw.window = ui.NewWindow("System Monitor Settings", 450, 430, false)
+	sw.window = ui.NewWindow("System Monitor Settings", 450, 900, false)
 	sw.window.SetMargined(true)
 	sw.window.OnClosing(func(*ui.Window) bool {
 		sw.window.Hide()
@@ -77,6 +94,81 @@ func (sw *SettingsWindow) initUI() {
 	sw.diskCheck.SetChecked(sw.appSettings.ShowDisk)
 	visibilityVBox.Append(sw.diskCheck, false)
 
+	// Processes checkbox
+	sw.processesCheck = ui.NewCheckbox("Show Top Processes")
+	sw.processesCheck.SetChecked(sw.appSettings.ShowProcesses)
+	visibilityVBox.Append(sw.processesCheck, false)
+
+	// Disk I/O checkbox
+	sw.diskIOCheck = ui.NewCheckbox("Show Top Disk I/O")
+	sw.diskIOCheck.SetChecked(sw.appSettings.ShowDiskIO)
+	visibilityVBox.Append(sw.diskIOCheck, false)
+
+	// Users checkbox
+	sw.usersCheck = ui.NewCheckbox("Show Usage by User")
+	sw.usersCheck.SetChecked(sw.appSettings.ShowUsers)
+	visibilityVBox.Append(sw.usersCheck, false)
+
+	// Services checkbox
+	sw.servicesCheck = ui.NewCheckbox("Show systemd Services")
+	sw.servicesCheck.SetChecked(sw.appSettings.ShowServices)
+	visibilityVBox.Append(sw.servicesCheck, false)
+
+	// Containers checkbox
+	sw.containersCheck = ui.NewCheckbox("Show Containers")
+	sw.containersCheck.SetChecked(sw.appSettings.ShowContainers)
+	visibilityVBox.Append(sw.containersCheck, false)
+
+	// System info checkbox
+	sw.systemInfoCheck = ui.NewCheckbox("Show System Info and Sessions")
+	sw.systemInfoCheck.SetChecked(sw.appSettings.ShowSystemInfo)
+	visibilityVBox.Append(sw.systemInfoCheck, false)
+
+	// Kernel activity checkbox
+	sw.kernelCheck = ui.NewCheckbox("Show Kernel Activity")
+	sw.kernelCheck.SetChecked(sw.appSettings.ShowKernel)
+	visibilityVBox.Append(sw.kernelCheck, false)
+
+	// File handles checkbox
+	sw.fileHandlesCheck = ui.NewCheckbox("Show Open Files and inotify Watches")
+	sw.fileHandlesCheck.SetChecked(sw.appSettings.ShowFileHandles)
+	visibilityVBox.Append(sw.fileHandlesCheck, false)
+
+	// Swap activity checkbox
+	sw.swapCheck = ui.NewCheckbox("Show Swap Activity and OOM Kills")
+	sw.swapCheck.SetChecked(sw.appSettings.ShowSwapActivity)
+	visibilityVBox.Append(sw.swapCheck, false)
+
+	// Storage health checkbox
+	sw.storageCheck = ui.NewCheckbox("Show Storage Health (RAID and Read-only Filesystems)")
+	sw.storageCheck.SetChecked(sw.appSettings.ShowStorageHealth)
+	visibilityVBox.Append(sw.storageCheck, false)
+
+	// Drive health checkbox
+	sw.driveHealthCheck = ui.NewCheckbox("Show SMART Disk Health")
+	sw.driveHealthCheck.SetChecked(sw.appSettings.ShowDriveHealth)
+	visibilityVBox.Append(sw.driveHealthCheck, false)
+
+	// CPU power checkbox
+	sw.powerCheck = ui.NewCheckbox("Show CPU Power (RAPL)")
+	sw.powerCheck.SetChecked(sw.appSettings.ShowPower)
+	visibilityVBox.Append(sw.powerCheck, false)
+
+	// Thermal throttling checkbox
+	sw.throttleCheck = ui.NewCheckbox("Warn When the CPU Is Throttled")
+	sw.throttleCheck.SetChecked(sw.appSettings.DetectThrottling)
+	visibilityVBox.Append(sw.throttleCheck, false)
+
+	// Maintenance checkbox
+	sw.maintenanceCheck = ui.NewCheckbox("Show Maintenance (Reboots, Failed Units, Updates)")
+	sw.maintenanceCheck.SetChecked(sw.appSettings.ShowMaintenance)
+	visibilityVBox.Append(sw.maintenanceCheck, false)
+
+	// Removable drives checkbox
+	sw.removableCheck = ui.NewCheckbox("Show USB Drives and SD Cards")
+	sw.removableCheck.SetChecked(sw.appSettings.ShowRemovableDrives)
+	visibilityVBox.Append(sw.removableCheck, false)
+
 	visibilityGroup.SetChild(visibilityVBox)
 	mainBox.Append(visibilityGroup, false)
 
@@ -128,6 +220,22 @@ func (sw *SettingsWindow) initUI() {
 	hboxDisk.Append(sw.diskTitleCheck, false)
 	taskbarVBox.Append(hboxDisk, false)
 
+	// Swap activity in taskbar
+	hboxSwap := ui.NewHorizontalBox()
+	hboxSwap.SetPadded(true)
+	sw.swapTitleCheck = ui.NewCheckbox("Show Swap Activity in Taskbar")
+	sw.swapTitleCheck.SetChecked(sw.appSettings.ShowSwapInTitle)
+	hboxSwap.Append(sw.swapTitleCheck, false)
+	taskbarVBox.Append(hboxSwap, false)
+
+	// CPU power in taskbar
+	hboxPower := ui.NewHorizontalBox()
+	hboxPower.SetPadded(true)
+	sw.powerTitleCheck = ui.NewCheckbox("Show CPU Power in Taskbar")
+	sw.powerTitleCheck.SetChecked(sw.appSettings.ShowPowerInTitle)
+	hboxPower.Append(sw.powerTitleCheck, false)
+	taskbarVBox.Append(hboxPower, false)
+
 	taskbarGroup.SetChild(taskbarVBox)
 	mainBox.Append(taskbarGroup, false)
 
@@ -187,6 +295,21 @@ func (sw *SettingsWindow) saveSettings() {
 	sw.appSettings.ShowMemory = sw.memoryCheck.Checked()
 	sw.appSettings.ShowNetwork = sw.networkCheck.Checked()
 	sw.appSettings.ShowDisk = sw.diskCheck.Checked()
+	sw.appSettings.ShowProcesses = sw.processesCheck.Checked()
+	sw.appSettings.ShowDiskIO = sw.diskIOCheck.Checked()
+	sw.appSettings.ShowUsers = sw.usersCheck.Checked()
+	sw.appSettings.ShowServices = sw.servicesCheck.Checked()
+	sw.appSettings.ShowContainers = sw.containersCheck.Checked()
+	sw.appSettings.ShowSystemInfo = sw.systemInfoCheck.Checked()
+	sw.appSettings.ShowKernel = sw.kernelCheck.Checked()
+	sw.appSettings.ShowFileHandles = sw.fileHandlesCheck.Checked()
+	sw.appSettings.ShowSwapActivity = sw.swapCheck.Checked()
+	sw.appSettings.ShowStorageHealth = sw.storageCheck.Checked()
+	sw.appSettings.ShowDriveHealth = sw.driveHealthCheck.Checked()
+	sw.appSettings.ShowPower = sw.powerCheck.Checked()
+	sw.appSettings.DetectThrottling = sw.throttleCheck.Checked()
+	sw.appSettings.ShowMaintenance = sw.maintenanceCheck.Checked()
+	sw.appSettings.ShowRemovableDrives = sw.removableCheck.Checked()
 
 	// Update taskbar title visibilities
 	sw.appSettings.ShowCPUInTitle = sw.cpuTitleCheck.Checked()
@@ -194,6 +317,8 @@ func (sw *SettingsWindow) saveSettings() {
 	sw.appSettings.ShowNetworkInTitle = sw.networkTitleCheck.Checked()
 	sw.appSettings.ShowBothNetworkSpeeds = sw.networkFullSpeedCheck.Checked()
 	sw.appSettings.ShowDiskInTitle = sw.diskTitleCheck.Checked()
+	sw.appSettings.ShowSwapInTitle = sw.swapTitleCheck.Checked()
+	sw.appSettings.ShowPowerInTitle = sw.powerTitleCheck.Checked()
 
 	// Update refresh interval
 	sw.appSettings.RefreshInterval = sw.refreshIntervalEntry.Value()
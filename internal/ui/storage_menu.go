@@ -0,0 +1,108 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/casper9429-kth/task_bar_monitor/internal/metrics"
+	"github.com/getlantern/systray"
+)
+
+// maxStorageItems is the number of arrays and read-only filesystems listed in the storage submenu
+const maxStorageItems = 10
+
+// storageMenu is the submenu showing software RAID state and read-only filesystems
+type storageMenu struct {
+	item  *systray.MenuItem
+	slots []*systray.MenuItem
+}
+
+// newStorageMenu creates the storage health submenu
+func newStorageMenu() *storageMenu {
+	m := &storageMenu{
+		item: systray.AddMenuItem("Storage health: Loading...", "Software RAID arrays and read-only filesystems"),
+	}
+	for n := 0; n < maxStorageItems; n++ {
+		slot := m.item.AddSubMenuItem("", "Storage health")
+		slot.Hide()
+		m.slots = append(m.slots, slot)
+	}
+	return m
+}
+
+// update shows the latest array states, followed by any read-only filesystems
+func (m *storageMenu) update(h metrics.StorageHealth) {
+	if problems := h.Problems(); problems > 0 {
+		m.item.SetTitle(fmt.Sprintf("Storage health: ⚠ %d %s", problems, plural(problems, "problem", "problems")))
+	} else {
+		m.item.SetTitle("Storage health: OK")
+	}
+
+	var lines []string
+	for _, a := range h.Arrays {
+		lines = append(lines, formatRAIDArray(a))
+	}
+	for _, r := range h.ReadOnly {
+		lines = append(lines, fmt.Sprintf("⚠ %s is read-only (%s, %s)", r.MountPoint, r.Device, r.FSType))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "No RAID arrays, all filesystems writable")
+	}
+
+	for n, slot := range m.slots {
+		if n < len(lines) {
+			slot.SetTitle(lines[n])
+			slot.Show()
+		} else {
+			slot.Hide()
+		}
+	}
+}
+
+// formatRAIDArray describes an array's state, e.g. "md0 (raid1): ⚠ degraded [1/2], recovery 8.5%, 75.3min left"
+func formatRAIDArray(a metrics.RAIDArray) string {
+	if !a.Active {
+		return fmt.Sprintf("%s: ⚠ inactive", a.Name)
+	}
+
+	state := "OK"
+	if a.Degraded() {
+		state = "⚠ degraded"
+	}
+	text := fmt.Sprintf("%s (%s): %s [%d/%d]", a.Name, a.Level, state, a.ActiveDevices, a.Devices)
+
+	if len(a.Failed) > 0 {
+		text += fmt.Sprintf(", %s failed", strings.Join(a.Failed, ", "))
+	}
+	if a.SyncAction != "" {
+		text += fmt.Sprintf(", %s %.1f%%, %s left", a.SyncAction, a.SyncProgress, a.SyncFinish)
+	}
+
+	return text
+}
+
+// formatStorageWarning returns a short title warning when an array is degraded or a filesystem is read-only, or ""
+func formatStorageWarning(h metrics.StorageHealth) string {
+	problems := h.Problems()
+	if problems == 0 {
+		return ""
+	}
+	if problems > 1 {
+		return fmt.Sprintf("⚠ storage (%d)", problems)
+	}
+
+	for _, a := range h.Arrays {
+		if a.Degraded() {
+			return fmt.Sprintf("⚠ %s degraded", a.Name)
+		}
+	}
+	return fmt.Sprintf("⚠ %s read-only", h.ReadOnly[0].MountPoint)
+}
+
+// plural returns one for a count of one and many otherwise
+func plural(count int, one, many string) string {
+	if count == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,50 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/casper9429-kth/task_bar_monitor/internal/metrics"
+	"github.com/getlantern/systray"
+)
+
+// swapMenu is the submenu showing swap, page fault and OOM killer activity
+type swapMenu struct {
+	item        *systray.MenuItem
+	swapInItem  *systray.MenuItem
+	swapOutItem *systray.MenuItem
+	faultsItem  *systray.MenuItem
+	oomItem     *systray.MenuItem
+}
+
+// newSwapMenu creates the swap activity submenu
+func newSwapMenu() *swapMenu {
+	m := &swapMenu{
+		item: systray.AddMenuItem("Swap activity: Loading...", "Virtual memory activity"),
+	}
+	m.swapInItem = m.item.AddSubMenuItem("Swap in: Loading...", "Data read back from swap")
+	m.swapOutItem = m.item.AddSubMenuItem("Swap out: Loading...", "Data written to swap")
+	m.faultsItem = m.item.AddSubMenuItem("Major page faults: Loading...", "Page faults that had to wait for the disk")
+	m.oomItem = m.item.AddSubMenuItem("OOM kills: Loading...", "Processes killed by the kernel to free memory")
+	return m
+}
+
+// update shows the latest virtual memory activity
+func (m *swapMenu) update(v metrics.VMActivity) {
+	// A process killed while we were watching is worth flagging until restart
+	title := fmt.Sprintf("Swap activity: ↓%s/s ↑%s/s",
+		metrics.FormatBytes(uint64(v.SwapInSpeed)), metrics.FormatBytes(uint64(v.SwapOutSpeed)))
+	if v.OOMKillsObserved > 0 {
+		title += fmt.Sprintf(" ⚠ %d OOM kills", v.OOMKillsObserved)
+	}
+	m.item.SetTitle(title)
+
+	m.swapInItem.SetTitle(fmt.Sprintf("Swap in: %s/s", metrics.FormatBytes(uint64(v.SwapInSpeed))))
+	m.swapOutItem.SetTitle(fmt.Sprintf("Swap out: %s/s", metrics.FormatBytes(uint64(v.SwapOutSpeed))))
+	m.faultsItem.SetTitle(fmt.Sprintf("Major page faults: %s/s", formatCount(v.MajorFaultRate)))
+	m.oomItem.SetTitle(fmt.Sprintf("OOM kills: %d since boot, %d while monitoring", v.OOMKills, v.OOMKillsObserved))
+}
+
+// formatSwapTitle returns the compact swap activity shown in the taskbar
+func formatSwapTitle(v metrics.VMActivity) string {
+	return fmt.Sprintf("S:↓%s ↑%s", metrics.FormatBytes(uint64(v.SwapInSpeed)), metrics.FormatBytes(uint64(v.SwapOutSpeed)))
+}